@@ -4,12 +4,16 @@ import (
 	"context"
 	"github.com/sethvargo/go-envconfig"
 	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/jobs"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
 	"github.com/zhughes3/go-accelerate/pkg/slog"
 )
 
 type appConfig struct {
-	LoggerConfig slog.Config     `env:",prefix=LOGGER_"`
-	DBConfig     postgres.Config `env:",prefix=DB_"`
+	LoggerConfig     slog.Config       `env:",prefix=LOGGER_"`
+	DBConfig         postgres.Config   `env:",prefix=DB_"`
+	JobsConfig       jobs.Config       `env:",prefix=JOBS_"`
+	PaginationConfig pagination.Config `env:",prefix=PAGINATION_"`
 }
 
 func readEnvConfig() (appConfig, error) {
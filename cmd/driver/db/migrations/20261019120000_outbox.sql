@@ -0,0 +1,21 @@
+-- +goose Up
+
+CREATE TABLE outbox(
+    id BIGSERIAL NOT NULL,
+    topic TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    delivered_at TIMESTAMPTZ,
+    dead_lettered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    PRIMARY KEY (id)
+);
+
+-- The relay only ever looks for messages that are still pending.
+CREATE INDEX outbox_pending_idx ON outbox (available_at, id) WHERE delivered_at IS NULL AND dead_lettered_at IS NULL;
+
+-- +goose Down
+
+DROP TABLE outbox;
@@ -0,0 +1,28 @@
+-- +goose Up
+
+CREATE TABLE jobs(
+    id BIGSERIAL NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    priority INT NOT NULL DEFAULT 0,
+    unique_key TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    locked_until TIMESTAMPTZ,
+    last_error TEXT,
+    completed_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+    PRIMARY KEY (id)
+);
+
+-- Only one unfinished job may have a given unique key.
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (unique_key) WHERE unique_key IS NOT NULL AND completed_at IS NULL AND failed_at IS NULL;
+
+-- Workers claim unfinished jobs by priority, then by when they are due.
+CREATE INDEX jobs_ready_idx ON jobs (priority DESC, run_at, id) WHERE completed_at IS NULL AND failed_at IS NULL;
+
+-- +goose Down
+
+DROP TABLE jobs;
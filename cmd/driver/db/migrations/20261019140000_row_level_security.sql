@@ -0,0 +1,42 @@
+-- +goose Up
+
+-- Rows are only visible to the user set in app.user_id, unless app.bypass_rls is on. Both are set locally on each
+-- transaction by the application. FORCE makes the policies apply to the table owner, which the application
+-- connects as.
+
+ALTER TABLE timelines ENABLE ROW LEVEL SECURITY;
+ALTER TABLE timelines FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY timelines_tenant_isolation ON timelines
+    USING (
+        current_setting('app.bypass_rls', true) = 'on'
+        OR user_id = current_setting('app.user_id', true)
+    )
+    WITH CHECK (
+        current_setting('app.bypass_rls', true) = 'on'
+        OR user_id = current_setting('app.user_id', true)
+    );
+
+ALTER TABLE events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE events FORCE ROW LEVEL SECURITY;
+
+-- Events belong to the owner of their timeline, whose visibility is itself restricted by timelines_tenant_isolation.
+CREATE POLICY events_tenant_isolation ON events
+    USING (
+        current_setting('app.bypass_rls', true) = 'on'
+        OR EXISTS (SELECT 1 FROM timelines WHERE timelines.id = events.timeline_id)
+    )
+    WITH CHECK (
+        current_setting('app.bypass_rls', true) = 'on'
+        OR EXISTS (SELECT 1 FROM timelines WHERE timelines.id = events.timeline_id)
+    );
+
+-- +goose Down
+
+DROP POLICY events_tenant_isolation ON events;
+ALTER TABLE events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE events DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY timelines_tenant_isolation ON timelines;
+ALTER TABLE timelines NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE timelines DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- Timelines are listed per user a page at a time, continuing after the creation time and ID of the last one.
+CREATE INDEX timelines_user_created_at_idx ON timelines (user_id, created_at, id);
+
+-- +goose Down
+
+DROP INDEX timelines_user_created_at_idx;
@@ -2,26 +2,46 @@ package main
 
 import (
 	"context"
+	"embed"
 	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/jobs"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/migrate"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/outbox"
 	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
 	"github.com/zhughes3/go-accelerate/internal/pkg/v1/iam"
 	"github.com/zhughes3/go-accelerate/internal/pkg/v1/server"
+	v1timelines "github.com/zhughes3/go-accelerate/internal/pkg/v1/timelines"
 	"github.com/zhughes3/go-accelerate/internal/pkg/v1/user"
 	"github.com/zhughes3/go-accelerate/pkg/app"
 	"github.com/zhughes3/go-accelerate/pkg/slog"
 	"github.com/zhughes3/go-accelerate/pkg/timelines"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 const serviceName = "driver"
 
+const (
+	pathDBStats   = "/db/stats"
+	migrationsDir = "db/migrations"
+)
+
+//go:embed db/migrations/*.sql
+var migrations embed.FS
+
 func main() {
 	config := mustReadEnvConfig()
 	logger := mustCreateLogger(config.LoggerConfig)
 
 	db := mustCreateDatabase(logger, config.DBConfig)
+	relay := mustStartOutboxRelay(logger, db)
+	jobPool := mustStartJobPool(logger, config.JobsConfig, db)
 	appServer := mustCreateAppServer(logger, config, db)
 
+	// The relay and the job workers use the database, so they must shut down first.
+	appServer.RegisterBeforeShutdownErrorHook(relay.Shutdown)
+	appServer.RegisterBeforeShutdownErrorHook(jobPool.Shutdown)
 	appServer.RegisterBeforeShutdownErrorHook(db.Shutdown)
 
 	if err := appServer.Run(context.Background()); err != nil {
@@ -30,7 +50,14 @@ func main() {
 }
 
 func mustCreateDatabase(logger slog.Logger, config postgres.Config) postgres.DB {
-	db, err := pgx.NewDBConnect(context.Background(), logger, &config)
+	// Connecting is retried until it succeeds, so stopping the process must interrupt it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := pgx.NewDBConnect(ctx, logger, &config,
+		pgx.WithMigrations(migrations, migrate.WithDir(migrationsDir)),
+		pgx.WithSessionSetting(postgres.SettingUserID, user.ResolveID),
+		pgx.WithQueries(v1timelines.Queries))
 	if err != nil {
 		logStaticFatalStartupError("Problem creating database", err)
 	}
@@ -38,6 +65,25 @@ func mustCreateDatabase(logger slog.Logger, config postgres.Config) postgres.DB
 	return db
 }
 
+func mustStartOutboxRelay(logger slog.Logger, db postgres.DB) *outbox.Relay {
+	relay := outbox.NewRelay(logger, db, outbox.NewStdoutPublisher())
+	if err := relay.Start(context.Background()); err != nil {
+		logStaticFatalStartupError("Problem starting outbox relay", err)
+	}
+
+	return relay
+}
+
+func mustStartJobPool(logger slog.Logger, config jobs.Config, db postgres.DB) *jobs.Pool {
+	// Job handlers are registered here, before the pool starts; with none, Start runs no workers.
+	pool := jobs.NewPool(logger, db, config)
+	if err := pool.Start(context.Background()); err != nil {
+		logStaticFatalStartupError("Problem starting job workers", err)
+	}
+
+	return pool
+}
+
 func mustReadEnvConfig() appConfig {
 	config, err := readEnvConfig()
 	if err != nil {
@@ -62,12 +108,15 @@ func mustCreateLogger(cfg slog.Config) slog.Logger {
 }
 
 func mustCreateAppServer(logger slog.Logger, cfg appConfig, db postgres.DB) *app.Server {
-	logger = logger.WithContextExtractor(user.IDExtractor)
+	logger = logger.WithContextExtractor(user.IDExtractor).WithContextExtractor(pgx.TxDepthExtractor)
 
-	timelinesService := timelines.NewService(logger, db)
+	timelinesService := timelines.NewService(logger, db, cfg.PaginationConfig)
 
 	appServer, err := app.NewServer(logger,
 		app.WithPProfEnabled(),
+		app.WithPrometheusEnabled(),
+		app.WithOperationalHandler(pathDBStats, postgres.NewStatsHandler(db)),
+		app.WithOpenAPIEnabled(serviceName, "Example application showcasing some of the go-accelerate packages."),
 		app.WithAuthMiddleware(iam.NewAuthMiddleware(logger)),
 		app.WithRequestHandlerSpecs(server.NewHandlerSpecs(user.MustResolveID, timelinesService)))
 	if err != nil {
@@ -5,3 +5,6 @@ import "context"
 // An Endpoint represents a single operation. Typical endpoint function implementations adapt a request
 // to a service call, and adapt the response from the service call to a response and error.
 type Endpoint func(ctx context.Context, request any) (any, error)
+
+// A TypedEndpoint is an [Endpoint] whose request and response types are checked at compile time.
+type TypedEndpoint[Req, Resp any] func(ctx context.Context, request Req) (Resp, error)
@@ -0,0 +1,354 @@
+package http
+
+import (
+	"encoding"
+	"fmt"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags understood by [BindRequestParams].
+const (
+	// tagPath binds a chi path parameter, e.g. `path:"timeline_id"`.
+	tagPath = "path"
+	// tagQuery binds a query string parameter, e.g. `query:"limit"`.
+	tagQuery = "query"
+	// tagParam binds a path parameter, falling back to the query string, e.g. `param:"id"`.
+	tagParam = "param"
+	// tagHeader binds a request header, e.g. `header:"X-Request-Id"`.
+	tagHeader = "header"
+	// tagCookie binds a cookie value, e.g. `cookie:"session"`.
+	tagCookie = "cookie"
+
+	// tagDefault is the raw value used when the parameter is not present on the request.
+	tagDefault = "default"
+	// tagRequired, when "true", makes a missing parameter an error.
+	tagRequired = "required"
+	// tagEnum is a comma separated list of the values that are allowed for the parameter.
+	tagEnum = "enum"
+	// tagLayout is the [time.Parse] layout used for [time.Time] fields. Defaults to [time.RFC3339].
+	tagLayout = "layout"
+)
+
+// paramSource looks up all the raw values for a named parameter on a request.
+type paramSource func(r *http.Request, name string) ([]string, bool)
+
+var paramSources = []struct {
+	tag    string
+	lookup paramSource
+}{
+	{tag: tagPath, lookup: pathParamValues},
+	{tag: tagQuery, lookup: urlParamValues},
+	{tag: tagParam, lookup: paramValues},
+	{tag: tagHeader, lookup: headerValues},
+	{tag: tagCookie, lookup: cookieValues},
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// BindRequestParams fills the exported fields of the struct pointed to by v from the path parameters,
+// query string, headers and cookies of the given [http.Request], based on the field's struct tags:
+//
+//	type listRequest struct {
+//		Limit   int      `query:"limit" default:"20"`
+//		OrderBy string   `query:"order_by" enum:"name,created_at"`
+//		IDs     []string `param:"id"`
+//	}
+//
+// Supported field types are strings, bools, ints, uints, floats, [time.Duration], [time.Time],
+// types implementing [encoding.TextUnmarshaler], and slices of any of them. Slices are populated from
+// repeated parameters as well as comma separated values. Values that can not be parsed result in an
+// [acerrors.InvalidInputError] naming the offending parameter.
+func BindRequestParams(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot bind request parameters into %T: a non-nil pointer to a struct is required", v)
+	}
+
+	return bindStruct(r, rv.Elem())
+}
+
+func bindStruct(r *http.Request, sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		lookup, name, found := determineParamSource(field)
+		if !found {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := bindStruct(r, sv.Field(i)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := bindField(r, sv.Field(i), field, lookup, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func determineParamSource(field reflect.StructField) (paramSource, string, bool) {
+	in, name, found := determineParamTag(field)
+	if !found {
+		return nil, "", false
+	}
+
+	for _, source := range paramSources {
+		if source.tag == in {
+			return source.lookup, name, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// A ParamDescriptor describes a struct field that is bound by [BindRequestParams].
+type ParamDescriptor struct {
+	// Name is the name of the parameter on the request.
+	Name string
+	// In is the struct tag naming where the parameter is read from, i.e. "path", "query", "param", "header" or "cookie".
+	In string
+	// Type is the type of the struct field.
+	Type       reflect.Type
+	Default    string
+	HasDefault bool
+	Required   bool
+	Enum       []string
+	// Layout is the time layout for [time.Time] fields.
+	Layout string
+}
+
+// DescribeRequestParams returns a description of every parameter that [BindRequestParams] binds into the
+// struct type t, in field order.
+func DescribeRequestParams(t reflect.Type) []ParamDescriptor {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var descriptors []ParamDescriptor
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		in, name, found := determineParamTag(field)
+		if !found {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				descriptors = append(descriptors, DescribeRequestParams(field.Type)...)
+			}
+			continue
+		}
+
+		def, hasDefault := field.Tag.Lookup(tagDefault)
+		d := ParamDescriptor{
+			Name:       name,
+			In:         in,
+			Type:       field.Type,
+			Default:    def,
+			HasDefault: hasDefault,
+			Required:   field.Tag.Get(tagRequired) == "true",
+			Layout:     field.Tag.Get(tagLayout),
+		}
+
+		if enum, ok := field.Tag.Lookup(tagEnum); ok {
+			d.Enum = strings.Split(enum, ",")
+		}
+
+		descriptors = append(descriptors, d)
+	}
+
+	return descriptors
+}
+
+func determineParamTag(field reflect.StructField) (string, string, bool) {
+	for _, source := range paramSources {
+		if name, ok := field.Tag.Lookup(source.tag); ok && acstrings.IsNotBlank(name) {
+			return source.tag, name, true
+		}
+	}
+
+	return "", "", false
+}
+
+func bindField(r *http.Request, fv reflect.Value, field reflect.StructField, lookup paramSource, name string) error {
+	values, found := lookup(r, name)
+	if found && allBlank(values) {
+		// like ParamValue, a blank value such as "?limit=" is as good as none, so the default applies
+		values, found = nil, false
+	}
+
+	if !found {
+		if def, ok := field.Tag.Lookup(tagDefault); ok {
+			values, found = []string{def}, true
+		}
+	}
+
+	if !found {
+		if field.Tag.Get(tagRequired) == "true" {
+			return acerrors.NewInvalidInputErrorf("Missing required request parameter: '%s'", name)
+		}
+
+		return nil
+	}
+
+	if fv.Kind() == reflect.Slice && !fv.Addr().Type().Implements(textUnmarshalerType) {
+		values = splitValues(values)
+	}
+
+	if err := checkEnum(field, name, values); err != nil {
+		return err
+	}
+
+	if err := setValues(fv, field, values); err != nil {
+		return acerrors.NewInvalidInputErrorf("Invalid value for request parameter '%s': %s", name, err.Error())
+	}
+
+	return nil
+}
+
+func allBlank(values []string) bool {
+	for _, v := range values {
+		if acstrings.IsNotBlank(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitValues(values []string) []string {
+	var split []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+
+	return split
+}
+
+func checkEnum(field reflect.StructField, name string, values []string) error {
+	raw, ok := field.Tag.Lookup(tagEnum)
+	if !ok {
+		return nil
+	}
+
+	allowed := strings.Split(raw, ",")
+	for _, v := range values {
+		if !slices.Contains(allowed, v) {
+			return acerrors.NewInvalidInputErrorf("Invalid value for request parameter '%s': '%s' must be one of [%s]", name, v, raw)
+		}
+	}
+
+	return nil
+}
+
+func setValues(fv reflect.Value, field reflect.StructField, values []string) error {
+	if fv.Kind() != reflect.Slice || fv.Addr().Type().Implements(textUnmarshalerType) {
+		if len(values) == 0 {
+			return nil
+		}
+
+		return setValue(fv, field, values[0])
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+	for i, v := range values {
+		if err := setValue(slice.Index(i), field, v); err != nil {
+			return err
+		}
+	}
+	fv.Set(slice)
+
+	return nil
+}
+
+func setValue(fv reflect.Value, field reflect.StructField, raw string) error {
+	if fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch fv.Type() {
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid duration", raw)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case timeType:
+		layout := time.RFC3339
+		if l, ok := field.Tag.Lookup(tagLayout); ok {
+			layout = l
+		}
+		t, err := time.Parse(layout, raw)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid time in the format '%s'", raw, layout)
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid boolean", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid integer", raw)
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid unsigned integer", raw)
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid number", raw)
+		}
+		fv.SetFloat(f)
+	case reflect.Pointer:
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), field, raw); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,357 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"io"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderAccept = "Accept"
+)
+
+const (
+	MediaTypeJSON        = "application/json"
+	MediaTypeMessagePack = "application/msgpack"
+	MediaTypeCBOR        = "application/cbor"
+	MediaTypeXML         = "application/xml"
+	MediaTypeCSV         = "text/csv"
+)
+
+// A Codec encodes and decodes values for a single media type.
+type Codec interface {
+	// MediaType is the media type, without parameters, handled by the codec, e.g. "application/json".
+	MediaType() string
+	// ContentType is the value of the Content-Type header written with encoded responses.
+	ContentType() string
+	// Decode reads a value from r into v.
+	Decode(r io.Reader, v any) error
+	// Encode writes v to w.
+	Encode(w io.Writer, v any) error
+}
+
+// A CodecRegistry holds the [Codec]s available to request handlers, keyed by media type.
+// The first registered codec is the default, used when a request doesn't specify a media type.
+type CodecRegistry struct {
+	codecs []Codec
+}
+
+// DefaultCodecs is the registry used by request handlers that have not been given one explicitly.
+var DefaultCodecs = NewCodecRegistry(JSONCodec{}, MessagePackCodec{}, CBORCodec{}, XMLCodec{}, CSVCodec{})
+
+func NewCodecRegistry(codecs ...Codec) *CodecRegistry {
+	r := &CodecRegistry{}
+	for _, c := range codecs {
+		r.Register(c)
+	}
+
+	return r
+}
+
+// Register adds the codec to the registry, replacing any codec previously registered for the same media type.
+func (r *CodecRegistry) Register(c Codec) {
+	for i, existing := range r.codecs {
+		if existing.MediaType() == c.MediaType() {
+			r.codecs[i] = c
+			return
+		}
+	}
+
+	r.codecs = append(r.codecs, c)
+}
+
+// Restrict returns a new registry containing only the codecs for the given media types.
+// When no media types are given the registry is returned as-is.
+func (r *CodecRegistry) Restrict(mediaTypes ...string) *CodecRegistry {
+	if len(mediaTypes) == 0 {
+		return r
+	}
+
+	restricted := &CodecRegistry{}
+	for _, c := range r.codecs {
+		if slices.Contains(mediaTypes, c.MediaType()) {
+			restricted.Register(c)
+		}
+	}
+
+	return restricted
+}
+
+// MediaTypes returns the media types of all the registered codecs, in order of registration.
+func (r *CodecRegistry) MediaTypes() []string {
+	mediaTypes := make([]string, 0, len(r.codecs))
+	for _, c := range r.codecs {
+		mediaTypes = append(mediaTypes, c.MediaType())
+	}
+
+	return mediaTypes
+}
+
+// ForContentType returns the codec that should decode a request body with the given Content-Type header.
+// An [acerrors.UnsupportedMediaTypeError] is returned when no codec matches.
+func (r *CodecRegistry) ForContentType(contentType string) (Codec, error) {
+	if len(r.codecs) == 0 {
+		return nil, acerrors.NewUnsupportedMediaTypeError("No media types are supported")
+	}
+
+	if acstrings.IsBlank(contentType) {
+		return r.codecs[0], nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, acerrors.NewUnsupportedMediaTypeErrorf("Invalid Content-Type: '%s'", contentType)
+	}
+
+	for _, c := range r.codecs {
+		if c.MediaType() == mediaType {
+			return c, nil
+		}
+	}
+
+	return nil, acerrors.NewUnsupportedMediaTypeErrorf("Unsupported Content-Type '%s', supported media types are: %s",
+		mediaType, strings.Join(r.MediaTypes(), ", "))
+}
+
+// ForAccept returns the codec that best matches the given Accept header, following the quality values of the most
+// specific media range matching each codec, as in RFC 9110 §12.5.1. Among codecs of equal quality, the ones named
+// by the header are preferred over the ones matched through a wildcard, and the default codec over the others, so
+// "*/*" gets the default codec while "application/msgpack;q=0.9, */*;q=0.8" gets MessagePack.
+// An [acerrors.NotAcceptableError] is returned when no codec matches.
+func (r *CodecRegistry) ForAccept(accept string) (Codec, error) {
+	if len(r.codecs) == 0 {
+		return nil, acerrors.NewNotAcceptableError("No media types are supported")
+	}
+
+	if acstrings.IsBlank(accept) {
+		return r.codecs[0], nil
+	}
+
+	ranges := parseAccept(accept)
+
+	var (
+		best      Codec
+		bestMatch mediaRange
+	)
+	for _, c := range r.codecs {
+		match, ok := bestRange(ranges, c.MediaType())
+		if !ok || match.quality <= 0 {
+			continue
+		}
+
+		// codecs are in order of registration, so the default codec wins ties of quality and specificity
+		if best == nil || match.quality > bestMatch.quality ||
+			match.quality == bestMatch.quality && match.specificity() > bestMatch.specificity() {
+			best, bestMatch = c, match
+		}
+	}
+
+	if best == nil {
+		return nil, acerrors.NewNotAcceptableErrorf("Cannot produce a response matching Accept '%s', supported media types are: %s",
+			accept, strings.Join(r.MediaTypes(), ", "))
+	}
+
+	return best, nil
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	if m.mediaType == "*/*" || m.mediaType == mediaType {
+		return true
+	}
+
+	prefix, found := strings.CutSuffix(m.mediaType, "/*")
+	return found && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// specificity ranks "*/*" below "type/*", below a full media type.
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// bestRange returns the most specific media range matching the media type, whose quality is the one of the type.
+func bestRange(ranges []mediaRange, mediaType string) (mediaRange, bool) {
+	var (
+		best  mediaRange
+		found bool
+	)
+	for _, m := range ranges {
+		if m.matches(mediaType) && (!found || m.specificity() > best.specificity()) {
+			best, found = m, true
+		}
+	}
+
+	return best, found
+}
+
+// parseAccept parses an Accept header into media ranges ordered by descending quality. Media ranges with a quality
+// of 0 are kept, since they make the media types they match not acceptable, e.g. "*/*, application/xml;q=0".
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	return ranges
+}
+
+type codecsContextKey struct{}
+
+type responseCodecContextKey struct{}
+
+// ContextWithCodecs returns a context carrying the [CodecRegistry] used by [DecodeRequestBody].
+func ContextWithCodecs(ctx context.Context, codecs *CodecRegistry) context.Context {
+	return context.WithValue(ctx, codecsContextKey{}, codecs)
+}
+
+func codecsFromContext(ctx context.Context) *CodecRegistry {
+	if codecs, ok := ctx.Value(codecsContextKey{}).(*CodecRegistry); ok {
+		return codecs
+	}
+
+	return DefaultCodecs
+}
+
+// ContextWithResponseCodec returns a context carrying the [Codec] negotiated for the response,
+// which is used by [EncodeResponse].
+func ContextWithResponseCodec(ctx context.Context, codec Codec) context.Context {
+	return context.WithValue(ctx, responseCodecContextKey{}, codec)
+}
+
+func responseCodecFromContext(ctx context.Context) Codec {
+	if codec, ok := ctx.Value(responseCodecContextKey{}).(Codec); ok {
+		return codec
+	}
+
+	return JSONCodec{}
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) MediaType() string {
+	return MediaTypeJSON
+}
+
+func (JSONCodec) ContentType() string {
+	return MediaTypeAppJSON
+}
+
+func (JSONCodec) Decode(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// MessagePackCodec encodes and decodes MessagePack, using the `json` struct tags of the values.
+type MessagePackCodec struct{}
+
+func (MessagePackCodec) MediaType() string {
+	return MediaTypeMessagePack
+}
+
+func (MessagePackCodec) ContentType() string {
+	return MediaTypeMessagePack
+}
+
+func (MessagePackCodec) Decode(r io.Reader, v any) error {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	decoder.DisallowUnknownFields(true)
+
+	return decoder.Decode(v)
+}
+
+func (MessagePackCodec) Encode(w io.Writer, v any) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	encoder.SetOmitEmpty(true)
+
+	return encoder.Encode(v)
+}
+
+// CBORCodec encodes and decodes CBOR, falling back to the `json` struct tags of the values.
+type CBORCodec struct{}
+
+func (CBORCodec) MediaType() string {
+	return MediaTypeCBOR
+}
+
+func (CBORCodec) ContentType() string {
+	return MediaTypeCBOR
+}
+
+func (CBORCodec) Decode(r io.Reader, v any) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
+
+func (CBORCodec) Encode(w io.Writer, v any) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+// XMLCodec encodes and decodes XML. Encoded values are always wrapped in a <response> root element,
+// since generic types like [api.ListResponse] do not have a valid XML element name.
+type XMLCodec struct{}
+
+const xmlRootElement = "response"
+
+func (XMLCodec) MediaType() string {
+	return MediaTypeXML
+}
+
+func (XMLCodec) ContentType() string {
+	return MediaTypeXML + "; charset=UTF-8"
+}
+
+func (XMLCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: xmlRootElement}})
+}
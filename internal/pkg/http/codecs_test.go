@@ -0,0 +1,44 @@
+package http
+
+import "testing"
+
+func TestCodecRegistryForAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{name: "no header", accept: "", want: MediaTypeJSON},
+		{name: "any", accept: "*/*", want: MediaTypeJSON},
+		{name: "exact", accept: "application/cbor", want: MediaTypeCBOR},
+		{name: "ranked above any", accept: "application/msgpack;q=0.9, */*;q=0.8", want: MediaTypeMessagePack},
+		{name: "ranked below any", accept: "application/msgpack;q=0.5, */*", want: MediaTypeJSON},
+		{name: "ranked", accept: "text/csv;q=0.5, application/json;q=0.4", want: MediaTypeCSV},
+		{name: "named over any of equal quality", accept: "*/*, application/xml", want: MediaTypeXML},
+		{name: "type wildcard", accept: "text/*", want: MediaTypeCSV},
+		{name: "excluded", accept: "application/json;q=0, */*", want: MediaTypeMessagePack},
+		{name: "browser", accept: "text/html,application/xml;q=0.9,*/*;q=0.8", want: MediaTypeXML},
+		{name: "browser without xml", accept: "text/html,application/xhtml+xml,*/*;q=0.8", want: MediaTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := DefaultCodecs.ForAccept(tt.accept)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if codec.MediaType() != tt.want {
+				t.Errorf("ForAccept(%q) = %s, want %s", tt.accept, codec.MediaType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCodecRegistryForAcceptNotAcceptable(t *testing.T) {
+	for _, accept := range []string{"text/html", "image/*", "application/json;q=0"} {
+		if _, err := DefaultCodecs.ForAccept(accept); err == nil {
+			t.Errorf("ForAccept(%q) should not be acceptable", accept)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A recordLister is a value, like [api.ListResponse], that holds a slice of records.
+type recordLister interface {
+	ListRecords() any
+}
+
+// CSVCodec encodes lists of records, either a slice of structs or a value implementing ListRecords,
+// e.g. [api.ListResponse], as CSV with a header row. Column names are taken from the `json` struct tags.
+// Decoding request bodies from CSV is not supported.
+type CSVCodec struct{}
+
+func (CSVCodec) MediaType() string {
+	return MediaTypeCSV
+}
+
+func (CSVCodec) ContentType() string {
+	return MediaTypeCSV + "; charset=UTF-8"
+}
+
+func (CSVCodec) Decode(_ io.Reader, _ any) error {
+	return acerrors.NewUnsupportedMediaTypeErrorf("Request bodies of type '%s' are not supported", MediaTypeCSV)
+}
+
+func (CSVCodec) Encode(w io.Writer, v any) error {
+	if lister, ok := v.(recordLister); ok {
+		v = lister.ListRecords()
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice {
+		return acerrors.NewNotAcceptableErrorf("Only lists can be encoded as '%s'", MediaTypeCSV)
+	}
+
+	elemType := rv.Type().Elem()
+	for elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct {
+		return acerrors.NewNotAcceptableErrorf("Only lists of records can be encoded as '%s'", MediaTypeCSV)
+	}
+
+	columns := csvColumns(elemType, nil)
+
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		record := reflect.Indirect(rv.Index(i))
+		row := make([]string, 0, len(columns))
+		for _, c := range columns {
+			cell, err := csvCell(record, c.index)
+			if err != nil {
+				return err
+			}
+			row = append(row, cell)
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvColumns returns the exported fields of t, flattening embedded structs the same way [encoding/json] does.
+func csvColumns(t reflect.Type, parent []int) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		index := append(append([]int{}, parent...), i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, csvColumns(field.Type, index)...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		columns = append(columns, csvColumn{name: name, index: index})
+	}
+
+	return columns
+}
+
+func csvCell(record reflect.Value, index []int) (string, error) {
+	fv, err := record.FieldByIndexErr(index)
+	if err != nil {
+		// a nil embedded pointer, render an empty cell
+		return "", nil
+	}
+
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return "", nil
+		}
+		fv = fv.Elem()
+	}
+
+	switch v := fv.Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return "", nil
+		}
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Map, reflect.Array:
+		// Nested values are rendered as JSON inside the cell.
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return fmt.Sprint(fv.Interface()), nil
+	}
+}
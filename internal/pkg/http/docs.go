@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// HandlerDocs optionally describes a [RequestHandlerSpec] for API documentation, e.g. an OpenAPI document.
+type HandlerDocs struct {
+	// Summary is a short summary of what the request handler does.
+	Summary string
+	// Description is a verbose explanation of the request handler behavior.
+	Description string
+	// Tags are used to group request handlers in the API documentation.
+	Tags []string
+
+	// ParamsType is a struct type whose fields are bound by [BindRequestParams].
+	ParamsType reflect.Type
+	// RequestType is the type of the decoded request body.
+	RequestType reflect.Type
+	// ResponseType is the type of the encoded response body.
+	ResponseType reflect.Type
+	// ResponseStatus is the status code of a successful response. Defaults to [http.StatusOK].
+	ResponseStatus int
+	// ErrorResponses maps the error status codes returned by the request handler to a description.
+	ErrorResponses map[int]string
+}
+
+// SuccessStatus returns the status code of a successful response.
+func (d HandlerDocs) SuccessStatus() int {
+	if d.ResponseStatus == 0 {
+		return http.StatusOK
+	}
+
+	return d.ResponseStatus
+}
+
+// WithSummary sets the summary and description of the request handler.
+func WithSummary(summary, description string) HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.docs.Summary = summary
+		h.docs.Description = description
+	}
+}
+
+// WithTags groups the request handler under the given tags.
+func WithTags(tags ...string) HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.docs.Tags = append(h.docs.Tags, tags...)
+	}
+}
+
+// WithRequestParams declares that the request handler binds its parameters into T with [BindRequestParams].
+func WithRequestParams[T any]() HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.docs.ParamsType = reflect.TypeFor[T]()
+	}
+}
+
+// WithRequestBody declares that the request handler decodes its request body into T.
+func WithRequestBody[T any]() HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.docs.RequestType = reflect.TypeFor[T]()
+	}
+}
+
+// WithResponseBody declares that the request handler responds with a T and the given status code.
+func WithResponseBody[T any](status int) HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.docs.ResponseType = reflect.TypeFor[T]()
+		h.docs.ResponseStatus = status
+	}
+}
+
+// WithNoContentResponse declares that the request handler responds without a body.
+func WithNoContentResponse() HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.docs.ResponseType = nil
+		h.docs.ResponseStatus = http.StatusNoContent
+	}
+}
+
+// WithErrorResponse declares an error status code that the request handler can respond with.
+// The body of the response is a [Non2XxResponse].
+func WithErrorResponse(status int, description string) HandlerSpecOption {
+	return func(h *handlerSpec) {
+		if h.docs.ErrorResponses == nil {
+			h.docs.ErrorResponses = map[int]string{}
+		}
+		h.docs.ErrorResponses[status] = description
+	}
+}
@@ -29,6 +29,11 @@ type RequestHandlerSpec interface {
 	Path() string
 	// Methods are the HTTP methods to which this request handler should respond.
 	Methods() []string
+	// MediaTypes restricts the [Codec]s that can be used to decode requests and encode responses.
+	// An empty slice allows every registered codec.
+	MediaTypes() []string
+	// Docs optionally describes the request handler for API documentation.
+	Docs() HandlerDocs
 }
 
 // A DecodeRequestFunc is a func responsible for decoding the parameters and/or body
@@ -36,17 +41,29 @@ type RequestHandlerSpec interface {
 type DecodeRequestFunc func(ctx context.Context, r *http.Request) (any, error)
 
 type handlerSpec struct {
-	name     string
-	decoder  DecodeRequestFunc
-	endpoint acendpoint.Endpoint
-	encoder  EncodeResponseFunc
-	path     string
-	methods  []string
+	name       string
+	decoder    DecodeRequestFunc
+	endpoint   acendpoint.Endpoint
+	encoder    EncodeResponseFunc
+	path       string
+	methods    []string
+	mediaTypes []string
+	docs       HandlerDocs
+}
+
+// A HandlerSpecOption configures optional behavior of a [RequestHandlerSpec].
+type HandlerSpecOption func(*handlerSpec)
+
+// WithMediaTypes restricts the request handler to the codecs for the given media types.
+func WithMediaTypes(mediaTypes ...string) HandlerSpecOption {
+	return func(h *handlerSpec) {
+		h.mediaTypes = append(h.mediaTypes, mediaTypes...)
+	}
 }
 
 func NewRequestHandlerSpec(name string, dec DecodeRequestFunc, e acendpoint.Endpoint,
-	enc EncodeResponseFunc, path string, methods []string) RequestHandlerSpec {
-	return handlerSpec{
+	enc EncodeResponseFunc, path string, methods []string, opts ...HandlerSpecOption) RequestHandlerSpec {
+	h := handlerSpec{
 		name:     name,
 		decoder:  dec,
 		endpoint: e,
@@ -54,6 +71,12 @@ func NewRequestHandlerSpec(name string, dec DecodeRequestFunc, e acendpoint.Endp
 		path:     path,
 		methods:  methods,
 	}
+
+	for _, o := range opts {
+		o(&h)
+	}
+
+	return h
 }
 
 func (h handlerSpec) Name() string {
@@ -80,27 +103,65 @@ func (h handlerSpec) Methods() []string {
 	return h.methods
 }
 
+func (h handlerSpec) MediaTypes() []string {
+	return h.mediaTypes
+}
+
+func (h handlerSpec) Docs() HandlerDocs {
+	return h.docs
+}
+
 type requestHandler struct {
 	endpoint     acendpoint.Endpoint
 	decoder      DecodeRequestFunc
 	encoder      EncodeResponseFunc
 	errorEncoder EncodeErrorFunc
 	errorHandler ErrorHandler
+	codecs       *CodecRegistry
 }
 
-func NewHandler(ep acendpoint.Endpoint, decoder DecodeRequestFunc, encoder EncodeResponseFunc) http.Handler {
-	return requestHandler{
+// A HandlerOption configures optional behavior of the [http.Handler] returned by [NewHandler].
+type HandlerOption func(*requestHandler)
+
+// WithCodecs sets the codecs used to negotiate the request and response media types.
+// Defaults to [DefaultCodecs].
+func WithCodecs(codecs *CodecRegistry) HandlerOption {
+	return func(h *requestHandler) {
+		h.codecs = codecs
+	}
+}
+
+func NewHandler(ep acendpoint.Endpoint, decoder DecodeRequestFunc, encoder EncodeResponseFunc, opts ...HandlerOption) http.Handler {
+	h := requestHandler{
 		endpoint:     ep,
 		decoder:      decoder,
 		encoder:      encoder,
 		errorEncoder: DefaultErrorEncoder,
 		// TODO add logging error handler
 		errorHandler: ErrorHandlerFunc(func(_ context.Context, _ error) {}),
+		codecs:       DefaultCodecs,
+	}
+
+	for _, o := range opts {
+		o(&h)
 	}
+
+	return h
 }
 
 func (h requestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := ContextWithCodecs(r.Context(), h.codecs)
+
+	// Negotiate the response media type before doing any work, so an unacceptable request has no side effects.
+	codec, err := h.codecs.ForAccept(r.Header.Get(HeaderAccept))
+	if err != nil {
+		h.errorHandler.Handle(ctx, err)
+		h.errorEncoder(ctx, err, w)
+		return
+	}
+
+	ctx = ContextWithResponseCodec(ctx, codec)
+	r = r.WithContext(ctx)
 
 	request, err := h.decoder(ctx, r)
 	if err != nil {
@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
 	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
@@ -27,6 +28,12 @@ func ParamValue(r *http.Request, paramName string) (string, bool) {
 	return urlParamValue(r, paramName)
 }
 
+// ParamValues returns all the values of the given path parameter, or when it is not a path parameter,
+// all the values of the query string parameter with the given name.
+func ParamValues(r *http.Request, paramName string) ([]string, bool) {
+	return paramValues(r, paramName)
+}
+
 func paramValues(r *http.Request, paramName string) ([]string, bool) {
 	if v, found := pathParamValue(r, paramName); found {
 		return []string{v}, found
@@ -41,6 +48,29 @@ func pathParamValue(r *http.Request, paramName string) (string, bool) {
 	return v, acstrings.IsNotBlank(v)
 }
 
+func pathParamValues(r *http.Request, paramName string) ([]string, bool) {
+	if v, found := pathParamValue(r, paramName); found {
+		return []string{v}, found
+	}
+
+	return nil, false
+}
+
+func headerValues(r *http.Request, name string) ([]string, bool) {
+	values := r.Header.Values(name)
+
+	return values, len(values) > 0
+}
+
+func cookieValues(r *http.Request, name string) ([]string, bool) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return []string{cookie.Value}, true
+}
+
 func urlParamValues(r *http.Request, paramName string) ([]string, bool) {
 	values, found := r.URL.Query()[paramName]
 
@@ -68,6 +98,30 @@ func DecodeJSONRequestBody(r *http.Request, v any) error {
 	return nil
 }
 
+// DecodeRequestBody decodes the body of the request into v, using the [Codec] that matches the request's
+// Content-Type header. The codecs available are those on the request's context, see [ContextWithCodecs].
+func DecodeRequestBody(r *http.Request, v any) error {
+	codec, err := codecsFromContext(r.Context()).ForContentType(r.Header.Get(HeaderContentType))
+	if err != nil {
+		return err
+	}
+
+	if err := codec.Decode(r.Body, v); err != nil {
+		if err == io.EOF {
+			return acerrors.NewInvalidInputErrorf("%s must be provided in the request body", codec.MediaType())
+		}
+
+		var statusCoder acerrors.StatusCoder
+		if errors.As(err, &statusCoder) {
+			return err
+		}
+
+		return acerrors.NewInvalidInputErrorf("Could not parse %s in the request body: %s", codec.MediaType(), err.Error())
+	}
+
+	return nil
+}
+
 func ClientIP(r *http.Request) string {
 	header := r.Header.Get("X-Forwarded-For")
 	ipAddList := strings.Split(header, ",")
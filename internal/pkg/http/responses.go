@@ -1,6 +1,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -17,8 +18,9 @@ const (
 )
 
 type Non2XxResponse struct {
-	StatusCode int    `json"-"`
-	Message    string `json:"message,omitempty"`
+	StatusCode int               `json:"-"`
+	Message    string            `json:"message,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
 }
 
 type EncodeErrorFunc func(context.Context, error, http.ResponseWriter)
@@ -31,7 +33,14 @@ func DefaultErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 		statusCode = statusCoder.StatusCode()
 	}
 
-	EncodeNon2XxResponse(w, statusCode, err.Error())
+	resp := Non2XxResponse{Message: err.Error()}
+
+	var detailer acerrors.Detailer
+	if errors.As(err, &detailer) {
+		resp.Details = detailer.Details()
+	}
+
+	_ = encodeJSONResponse(w, statusCode, resp)
 }
 
 func EncodeNon2XxResponse(w http.ResponseWriter, code int, message string) {
@@ -49,12 +58,38 @@ func EncodeJSONCreatedResponse(_ context.Context, w http.ResponseWriter, resp an
 	return encodeJSONResponse(w, http.StatusCreated, resp)
 }
 
+// EncodeResponse encodes resp using the [Codec] negotiated from the request's Accept header.
+func EncodeResponse(ctx context.Context, w http.ResponseWriter, resp any) error {
+	return encodeResponse(ctx, w, http.StatusOK, resp)
+}
+
+// EncodeCreatedResponse encodes resp using the [Codec] negotiated from the request's Accept header.
+func EncodeCreatedResponse(ctx context.Context, w http.ResponseWriter, resp any) error {
+	return encodeResponse(ctx, w, http.StatusCreated, resp)
+}
+
 func EncodeNoContentResponse(_ context.Context, w http.ResponseWriter, _ any) error {
 	w.WriteHeader(http.StatusNoContent)
 
 	return nil
 }
 
+func encodeResponse(ctx context.Context, w http.ResponseWriter, status int, resp any) error {
+	codec := responseCodecFromContext(ctx)
+
+	// Encode into a buffer first so an encoding failure can still be reported with an error status code.
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, resp); err != nil {
+		return err
+	}
+
+	w.Header().Set(HeaderContentType, codec.ContentType())
+	w.WriteHeader(status)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
 func encodeJSONResponse(w http.ResponseWriter, status int, resp any) error {
 	w.Header().Set(HeaderContentType, MediaTypeAppJSON)
 	w.WriteHeader(status)
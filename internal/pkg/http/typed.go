@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	acendpoint "github.com/zhughes3/go-accelerate/internal/pkg/endpoint"
+	"net/http"
+	"reflect"
+)
+
+// A TypedDecodeRequestFunc is a [DecodeRequestFunc] that returns a concrete request type.
+type TypedDecodeRequestFunc[Req any] func(ctx context.Context, r *http.Request) (Req, error)
+
+// A TypedEncodeResponseFunc is an [EncodeResponseFunc] that accepts a concrete response type.
+type TypedEncodeResponseFunc[Resp any] func(ctx context.Context, w http.ResponseWriter, resp Resp) error
+
+// NewTypedRequestHandlerSpec creates a [RequestHandlerSpec] whose decoder, endpoint and encoder are checked
+// against each other at compile time, instead of relying on type assertions of the request inside the endpoint.
+// The response type is declared in the spec's [HandlerDocs], unless overridden by one of the opts.
+func NewTypedRequestHandlerSpec[Req, Resp any](name string, dec TypedDecodeRequestFunc[Req], e acendpoint.TypedEndpoint[Req, Resp],
+	enc TypedEncodeResponseFunc[Resp], path string, methods []string, opts ...HandlerSpecOption) RequestHandlerSpec {
+	opts = append([]HandlerSpecOption{func(h *handlerSpec) {
+		h.docs.ResponseType = reflect.TypeFor[Resp]()
+	}}, opts...)
+
+	return NewRequestHandlerSpec(name, untypedDecoder(dec), untypedEndpoint(e), untypedEncoder(enc), path, methods, opts...)
+}
+
+// TypedEncoder adapts an [EncodeResponseFunc], which accepts any response, to a [TypedEncodeResponseFunc].
+func TypedEncoder[Resp any](enc EncodeResponseFunc) TypedEncodeResponseFunc[Resp] {
+	return func(ctx context.Context, w http.ResponseWriter, resp Resp) error {
+		return enc(ctx, w, resp)
+	}
+}
+
+// EncodeTypedResponse is the typed equivalent of [EncodeResponse].
+func EncodeTypedResponse[Resp any](ctx context.Context, w http.ResponseWriter, resp Resp) error {
+	return EncodeResponse(ctx, w, resp)
+}
+
+// EncodeTypedCreatedResponse is the typed equivalent of [EncodeCreatedResponse].
+func EncodeTypedCreatedResponse[Resp any](ctx context.Context, w http.ResponseWriter, resp Resp) error {
+	return EncodeCreatedResponse(ctx, w, resp)
+}
+
+// EncodeTypedNoContentResponse is the typed equivalent of [EncodeNoContentResponse].
+func EncodeTypedNoContentResponse[Resp any](ctx context.Context, w http.ResponseWriter, resp Resp) error {
+	return EncodeNoContentResponse(ctx, w, resp)
+}
+
+func untypedDecoder[Req any](dec TypedDecodeRequestFunc[Req]) DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (any, error) {
+		return dec(ctx, r)
+	}
+}
+
+func untypedEndpoint[Req, Resp any](e acendpoint.TypedEndpoint[Req, Resp]) acendpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(Req)
+		if !ok {
+			// Only possible when the untyped endpoint is paired with a different decoder.
+			return nil, fmt.Errorf("endpoint expected a request of type %T but got %T", req, request)
+		}
+
+		return e(ctx, req)
+	}
+}
+
+func untypedEncoder[Resp any](enc TypedEncodeResponseFunc[Resp]) EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, resp any) error {
+		if resp == nil {
+			var zero Resp
+			return enc(ctx, w, zero)
+		}
+
+		typed, ok := resp.(Resp)
+		if !ok {
+			return fmt.Errorf("encoder expected a response of type %T but got %T", typed, resp)
+		}
+
+		return enc(ctx, w, typed)
+	}
+}
@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	achttp "github.com/zhughes3/go-accelerate/internal/pkg/http"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	inPath   = "path"
+	inQuery  = "query"
+	inHeader = "header"
+	inCookie = "cookie"
+)
+
+var (
+	// chiParamPattern matches chi path parameters, which may include a regular expression, e.g. {id:[0-9]+}.
+	chiParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?}`)
+
+	non2XxResponseType = reflect.TypeFor[achttp.Non2XxResponse]()
+)
+
+// NewDocument generates an OpenAPI document describing the given request handler specs by reflecting over the
+// types declared in their [achttp.HandlerDocs]. The pathPrefix is the path the specs are mounted under, and
+// codecs are the codecs available to the specs, used to list the media types of each request and response.
+func NewDocument(info Info, pathPrefix string, codecs *achttp.CodecRegistry, specs []achttp.RequestHandlerSpec) *Document {
+	g := generator{
+		registry: newSchemaRegistry(),
+		codecs:   codecs,
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+	}
+
+	if pathPrefix != "" && pathPrefix != "/" {
+		doc.Servers = []Server{{URL: strings.TrimRight(pathPrefix, "/")}}
+	}
+
+	var tags []string
+	for _, spec := range specs {
+		path := chiParamPattern.ReplaceAllString(spec.Path(), "{$1}")
+		if _, ok := doc.Paths[path]; !ok {
+			doc.Paths[path] = PathItem{}
+		}
+
+		for _, method := range spec.Methods() {
+			doc.Paths[path][strings.ToLower(method)] = g.operation(spec, path, operationID(spec, method))
+		}
+
+		for _, tag := range spec.Docs().Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	for _, tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+
+	doc.Components.Schemas = g.registry.schemas
+
+	return doc
+}
+
+type generator struct {
+	registry *schemaRegistry
+	codecs   *achttp.CodecRegistry
+}
+
+// operationID returns the name of the spec, suffixed with the method when the spec handles several methods, since
+// every operation of the document must have its own id.
+func operationID(spec achttp.RequestHandlerSpec, method string) string {
+	if len(spec.Methods()) <= 1 {
+		return spec.Name()
+	}
+
+	return spec.Name() + "_" + strings.ToLower(method)
+}
+
+func (g generator) operation(spec achttp.RequestHandlerSpec, path, id string) *Operation {
+	docs := spec.Docs()
+	mediaTypes := g.codecs.Restrict(spec.MediaTypes()...).MediaTypes()
+
+	op := &Operation{
+		OperationID: id,
+		Summary:     docs.Summary,
+		Description: docs.Description,
+		Tags:        docs.Tags,
+		Parameters:  g.parameters(docs, path),
+		Responses:   map[string]Response{},
+	}
+
+	if docs.RequestType != nil {
+		// CSV can only be used to encode lists, it can never decode a request body.
+		requestMediaTypes := slices.DeleteFunc(slices.Clone(mediaTypes), func(mt string) bool {
+			return mt == achttp.MediaTypeCSV
+		})
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  g.content(requestMediaTypes, docs.RequestType),
+		}
+	}
+
+	success := Response{Description: http.StatusText(docs.SuccessStatus())}
+	if docs.ResponseType != nil {
+		success.Content = g.content(mediaTypes, docs.ResponseType)
+	}
+	op.Responses[strconv.Itoa(docs.SuccessStatus())] = success
+
+	for status, description := range docs.ErrorResponses {
+		op.Responses[strconv.Itoa(status)] = g.errorResponse(description)
+	}
+	op.Responses["default"] = g.errorResponse("Unexpected error")
+
+	return op
+}
+
+func (g generator) content(mediaTypes []string, t reflect.Type) map[string]MediaType {
+	content := map[string]MediaType{}
+	schema := g.registry.schemaFor(t)
+	for _, mt := range mediaTypes {
+		content[mt] = MediaType{Schema: schema}
+	}
+
+	return content
+}
+
+func (g generator) errorResponse(description string) Response {
+	// Errors are always encoded as JSON by the default error encoder.
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{achttp.MediaTypeJSON: {Schema: g.registry.schemaFor(non2XxResponseType)}},
+	}
+}
+
+func (g generator) parameters(docs achttp.HandlerDocs, path string) []Parameter {
+	var params []Parameter
+	documented := map[string]bool{}
+
+	if docs.ParamsType != nil {
+		for _, d := range achttp.DescribeRequestParams(docs.ParamsType) {
+			p := Parameter{
+				Name:     d.Name,
+				In:       determineIn(d.In, d.Name, path),
+				Required: d.Required,
+				Schema:   g.registry.schemaFor(d.Type),
+			}
+
+			if p.In == inPath {
+				p.Required = true
+				documented[d.Name] = true
+			}
+
+			if d.HasDefault {
+				p.Schema.Default = parseDefault(p.Schema, d.Default)
+			}
+
+			if len(d.Enum) > 0 {
+				if p.Schema.Type == "array" {
+					p.Schema.Items.Enum = d.Enum
+				} else {
+					p.Schema.Enum = d.Enum
+				}
+			}
+
+			params = append(params, p)
+		}
+	}
+
+	// Every path parameter must be documented, even when the request handler doesn't declare its params.
+	for _, match := range chiParamPattern.FindAllStringSubmatch(path, -1) {
+		if !documented[match[1]] {
+			params = append(params, Parameter{Name: match[1], In: inPath, Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	return params
+}
+
+// determineIn maps a binding struct tag to where the parameter is located in the OpenAPI document.
+func determineIn(tag, name, path string) string {
+	switch tag {
+	case "path":
+		return inPath
+	case "header":
+		return inHeader
+	case "cookie":
+		return inCookie
+	case "param":
+		if strings.Contains(path, "{"+name+"}") {
+			return inPath
+		}
+		return inQuery
+	default:
+		return inQuery
+	}
+}
+
+// parseDefault converts the raw default value of a parameter to the JSON type of its schema.
+func parseDefault(schema *Schema, raw string) any {
+	switch schema.Type {
+	case "integer":
+		if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+
+	return raw
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+// The Redoc bundle is embedded and served by the application, so the UI runs no script from a third party origin
+// and works offline and behind a restrictive Content-Security-Policy.
+//go:generate curl -sSfL -o ui/redoc.standalone.js https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js
+
+//go:embed ui
+var ui embed.FS
+
+const uiBundlePath = "ui/redoc.standalone.js"
+
+// ErrNoUIBundle is returned by [NewUIBundleHandler] when the Redoc bundle was not downloaded before building.
+var ErrNoUIBundle = errors.New("the Redoc bundle is not embedded, run 'go generate ./internal/pkg/openapi' before building")
+
+var uiTemplate = template.Must(template.ParseFS(ui, "ui/redoc.html"))
+
+// NewDocumentHandler returns an [http.Handler] that serves the document as JSON.
+// The document is encoded once, up front, since it never changes after the server starts.
+func NewDocumentHandler(doc *Document) (http.Handler, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return staticHandler{contentType: "application/json; charset=utf-8", body: b}, nil
+}
+
+// NewUIHandler returns an [http.Handler] that serves a Redoc page rendering the document found at specURL, with the
+// Redoc bundle served by [NewUIBundleHandler] at bundleURL.
+func NewUIHandler(title, specURL, bundleURL string) (http.Handler, error) {
+	var buf bytes.Buffer
+	if err := uiTemplate.Execute(&buf, struct {
+		Title     string
+		SpecURL   string
+		BundleURL string
+	}{Title: title, SpecURL: specURL, BundleURL: bundleURL}); err != nil {
+		return nil, err
+	}
+
+	return staticHandler{contentType: "text/html; charset=utf-8", body: buf.Bytes()}, nil
+}
+
+// NewUIBundleHandler returns an [http.Handler] that serves the embedded Redoc bundle loaded by the page of
+// [NewUIHandler]. [ErrNoUIBundle] is returned when the bundle is not embedded.
+func NewUIBundleHandler() (http.Handler, error) {
+	b, err := fs.ReadFile(ui, uiBundlePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoUIBundle
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return staticHandler{contentType: "text/javascript; charset=utf-8", body: b}, nil
+}
+
+type staticHandler struct {
+	contentType string
+	body        []byte
+}
+
+func (h staticHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", h.contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(h.body)
+}
@@ -0,0 +1,173 @@
+package openapi
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const componentsSchemaRef = "#/components/schemas/"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// invalidComponentChars matches characters that are not allowed in a component name.
+	invalidComponentChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// A schemaRegistry reflects over Go types to build JSON Schemas. Named struct types are registered as
+// reusable components and referenced with a $ref, so recursive and shared types are only described once.
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// schemaFor returns the schema of values of type t, as encoded by [encoding/json].
+func (r *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return r.structRef(t)
+	default:
+		// interfaces and other dynamic values can hold anything
+		return &Schema{}
+	}
+}
+
+func (r *schemaRegistry) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return r.structSchema(t)
+	}
+
+	if name, ok := r.names[t]; ok {
+		return &Schema{Ref: componentsSchemaRef + name}
+	}
+
+	name := r.componentName(t)
+	r.names[t] = name
+
+	// Register a placeholder first so recursive types terminate.
+	r.schemas[name] = &Schema{}
+	*r.schemas[name] = *r.structSchema(t)
+
+	return &Schema{Ref: componentsSchemaRef + name}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	r.addFields(s, t)
+
+	return s
+}
+
+// addFields adds the JSON encoded fields of t to s, flattening embedded structs the same way [encoding/json] does.
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				r.addFields(s, fieldType)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = r.schemaFor(field.Type)
+
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// componentName returns a unique, valid component name for the named type t. Type parameters are reduced to
+// their unqualified names, e.g. "ListResponse[github.com/x/api.Timeline]" becomes "ListResponse_Timeline", and
+// types with the same name from different packages are prefixed with their package name.
+func (r *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if base, params, found := strings.Cut(name, "["); found {
+		params = strings.TrimSuffix(params, "]")
+		var short []string
+		for _, p := range strings.Split(params, ",") {
+			short = append(short, p[strings.LastIndex(p, ".")+1:])
+		}
+		name = base + "_" + strings.Join(short, "_")
+	}
+	name = invalidComponentChars.ReplaceAllString(name, "_")
+
+	if _, taken := r.schemas[name]; !taken {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	qualified := invalidComponentChars.ReplaceAllString(pkg[strings.LastIndex(pkg, "/")+1:], "_") + "." + name
+	candidate := qualified
+	for i := 2; ; i++ {
+		if _, taken := r.schemas[candidate]; !taken {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s%d", qualified, i)
+	}
+}
@@ -0,0 +1,522 @@
+package postgres
+
+import (
+	"fmt"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The builders compose statements from SQL fragments, in which every "?" is a placeholder for the next argument.
+// Placeholders are numbered when the statement is built, e.g. "title LIKE ?" becomes "title LIKE $3" when two
+// arguments come before it, so fragments can be combined in any order. A literal question mark, such as the JSONB
+// operator, is written "??".
+//
+// Fragments are trusted SQL written by the developer. Values from requests must only ever be passed as arguments,
+// or as orders checked against a [SortAllowlist].
+
+// A Predicate is a condition of a WHERE clause, built with [Cond], [In], [And], [Or] or [If].
+type Predicate struct {
+	sql  string
+	args []any
+	// skip drops the predicate from the clause it is part of
+	skip bool
+}
+
+// Cond is the condition sql, with a "?" placeholder for each of the args, e.g. Cond("created_at >= ?", since).
+func Cond(sql string, args ...any) Predicate {
+	return Predicate{sql: sql, args: args}
+}
+
+// In is the condition that the column is one of the values, which must be a slice or an array. It is always false
+// when there are no values.
+func In(column string, values any) Predicate {
+	args := expandValues(values)
+	if len(args) == 0 {
+		return Predicate{sql: "FALSE"}
+	}
+
+	return Predicate{sql: column + " IN (" + strings.Repeat("?, ", len(args)-1) + "?)", args: args}
+}
+
+// If is the predicate when ok is true, and is dropped from its clause otherwise. It is used for optional filters:
+//
+//	Where(If(prefix != "", Cond("title LIKE ?", prefix+"%")))
+func If(ok bool, p Predicate) Predicate {
+	if !ok {
+		return Predicate{skip: true}
+	}
+
+	return p
+}
+
+// And is the conjunction of the predicates, skipping dropped ones. It is dropped itself when all of them are.
+func And(predicates ...Predicate) Predicate {
+	return join(" AND ", predicates)
+}
+
+// Or is the disjunction of the predicates, skipping dropped ones. It is dropped itself when all of them are.
+func Or(predicates ...Predicate) Predicate {
+	return join(" OR ", predicates)
+}
+
+func join(separator string, predicates []Predicate) Predicate {
+	var (
+		parts []string
+		args  []any
+	)
+
+	for _, p := range predicates {
+		if p.skip {
+			continue
+		}
+		parts = append(parts, p.sql)
+		args = append(args, p.args...)
+	}
+
+	switch len(parts) {
+	case 0:
+		return Predicate{skip: true}
+	case 1:
+		return Predicate{sql: parts[0], args: args}
+	default:
+		return Predicate{sql: "(" + strings.Join(parts, ")"+separator+"(") + ")", args: args}
+	}
+}
+
+func expandValues(values any) []any {
+	v := reflect.ValueOf(values)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []any{values}
+	}
+
+	args := make([]any, v.Len())
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+
+	return args
+}
+
+// A SortAllowlist maps the names clients may sort by to the SQL expressions they sort on.
+type SortAllowlist map[string]string
+
+// orderBy converts a comma separated list of names, each prefixed with "-" for a descending order, into the
+// expressions of an ORDER BY clause, rejecting names missing from the allowlist.
+func (a SortAllowlist) orderBy(order string) ([]string, error) {
+	var exprs []string
+
+	for _, name := range strings.Split(order, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		direction := "ASC"
+		if trimmed, ok := strings.CutPrefix(name, "-"); ok {
+			name, direction = trimmed, "DESC"
+		}
+
+		expr, ok := a[name]
+		if !ok {
+			return nil, acerrors.NewInvalidInputErrorf("cannot sort by '%s'", name)
+		}
+		exprs = append(exprs, expr+" "+direction)
+	}
+
+	return exprs, nil
+}
+
+// statement accumulates the SQL and the arguments of a statement being built.
+type statement struct {
+	name string
+	sql  strings.Builder
+	args []any
+	err  error
+}
+
+func (s *statement) write(sql string, args ...any) {
+	s.sql.WriteString(sql)
+	s.args = append(s.args, args...)
+}
+
+func (s *statement) writeWhere(predicates []Predicate) {
+	if where := And(predicates...); !where.skip {
+		s.write(" WHERE "+where.sql, where.args...)
+	}
+}
+
+func (s *statement) writeReturning(columns []string) {
+	if len(columns) > 0 {
+		s.write(" RETURNING " + strings.Join(columns, ", "))
+	}
+}
+
+// build numbers the placeholders of the statement, and prefixes its name, if any, so the query is labeled like
+// those of a [QueryRegistry].
+func (s *statement) build() (string, []any, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+
+	sql, n := numberPlaceholders(s.sql.String())
+	if n != len(s.args) {
+		return "", nil, fmt.Errorf("statement has %d placeholders but %d arguments: %s", n, len(s.args), sql)
+	}
+
+	if acstrings.IsNotBlank(s.name) {
+		sql = queryNamePrefix + " " + s.name + "\n" + sql
+	}
+
+	return sql, s.args, nil
+}
+
+// numberPlaceholders replaces every "?" with "$1", "$2", and so on, and every "??" with "?". It returns the number
+// of placeholders.
+func numberPlaceholders(sql string) (string, int) {
+	var (
+		b strings.Builder
+		n int
+	)
+
+	for i := 0; i < len(sql); i++ {
+		if sql[i] != '?' {
+			b.WriteByte(sql[i])
+			continue
+		}
+
+		if i+1 < len(sql) && sql[i+1] == '?' {
+			b.WriteByte('?')
+			i++
+			continue
+		}
+
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return b.String(), n
+}
+
+// A SelectBuilder builds a SELECT statement. Create one with [Select].
+type SelectBuilder struct {
+	name     string
+	columns  []string
+	from     string
+	joins    []Predicate
+	where    []Predicate
+	groupBy  []string
+	orderBy  []string
+	limit    *int
+	offset   *int
+	suffixes []Predicate
+	err      error
+}
+
+// Select starts a SELECT statement of the columns.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// Named names the statement, so its logs and metrics are labeled with the name rather than the SQL.
+func (b *SelectBuilder) Named(name string) *SelectBuilder {
+	b.name = name
+	return b
+}
+
+// From sets the table, or table expression, selected from.
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.from = table
+	return b
+}
+
+// Join adds a join clause, e.g. Join("JOIN events e ON e.timeline_id = t.id").
+func (b *SelectBuilder) Join(join string, args ...any) *SelectBuilder {
+	b.joins = append(b.joins, Cond(join, args...))
+	return b
+}
+
+// Where adds predicates the rows must match. Predicates added by every call are combined with AND.
+func (b *SelectBuilder) Where(predicates ...Predicate) *SelectBuilder {
+	b.where = append(b.where, predicates...)
+	return b
+}
+
+// GroupBy adds expressions to group the rows by.
+func (b *SelectBuilder) GroupBy(exprs ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, exprs...)
+	return b
+}
+
+// OrderBy adds trusted expressions to order the rows by, e.g. "created_at DESC".
+func (b *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, exprs...)
+	return b
+}
+
+// SortBy orders the rows by an order from a request, e.g. "name,-created_at", whose names must be in the allowlist.
+// Building the statement returns an [acerrors.InvalidInputError] otherwise. A blank order adds nothing.
+func (b *SelectBuilder) SortBy(order string, allowlist SortAllowlist) *SelectBuilder {
+	exprs, err := allowlist.orderBy(order)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	return b.OrderBy(exprs...)
+}
+
+// Limit caps the number of rows returned.
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = &limit
+	return b
+}
+
+// Offset skips the first rows.
+func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
+	b.offset = &offset
+	return b
+}
+
+// Suffix appends a clause to the statement, e.g. Suffix("FOR UPDATE SKIP LOCKED").
+func (b *SelectBuilder) Suffix(sql string, args ...any) *SelectBuilder {
+	b.suffixes = append(b.suffixes, Cond(sql, args...))
+	return b
+}
+
+// Build returns the SQL and the arguments of the statement, to be run with the query helpers, e.g.
+// pgx.ScanAllContext[T](ctx, sql, args...).
+func (b *SelectBuilder) Build() (string, []any, error) {
+	s := statement{name: b.name, err: b.err}
+
+	if len(b.columns) == 0 {
+		return "", nil, fmt.Errorf("select statement has no columns")
+	}
+
+	s.write("SELECT " + strings.Join(b.columns, ", "))
+	if acstrings.IsNotBlank(b.from) {
+		s.write(" FROM " + b.from)
+	}
+	for _, join := range b.joins {
+		s.write(" "+join.sql, join.args...)
+	}
+	s.writeWhere(b.where)
+	if len(b.groupBy) > 0 {
+		s.write(" GROUP BY " + strings.Join(b.groupBy, ", "))
+	}
+	if len(b.orderBy) > 0 {
+		s.write(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	if b.limit != nil {
+		s.write(" LIMIT ?", *b.limit)
+	}
+	if b.offset != nil {
+		s.write(" OFFSET ?", *b.offset)
+	}
+	for _, suffix := range b.suffixes {
+		s.write(" "+suffix.sql, suffix.args...)
+	}
+
+	return s.build()
+}
+
+// An InsertBuilder builds an INSERT statement. Create one with [InsertInto].
+type InsertBuilder struct {
+	name      string
+	table     string
+	columns   []string
+	rows      [][]any
+	suffixes  []Predicate
+	returning []string
+}
+
+// InsertInto starts an INSERT statement into the table.
+func InsertInto(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+// Named names the statement, so its logs and metrics are labeled with the name rather than the SQL.
+func (b *InsertBuilder) Named(name string) *InsertBuilder {
+	b.name = name
+	return b
+}
+
+// Columns sets the columns the values are inserted into.
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = columns
+	return b
+}
+
+// Values adds a row of values, one for each column. It may be called several times to insert several rows.
+func (b *InsertBuilder) Values(values ...any) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Suffix appends a clause to the statement, e.g. Suffix("ON CONFLICT (id) DO NOTHING").
+func (b *InsertBuilder) Suffix(sql string, args ...any) *InsertBuilder {
+	b.suffixes = append(b.suffixes, Cond(sql, args...))
+	return b
+}
+
+// Returning sets the columns of the inserted rows returned by the statement.
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning = columns
+	return b
+}
+
+// Build returns the SQL and the arguments of the statement.
+func (b *InsertBuilder) Build() (string, []any, error) {
+	if len(b.columns) == 0 || len(b.rows) == 0 {
+		return "", nil, fmt.Errorf("insert into %s has no columns or values", b.table)
+	}
+
+	s := statement{name: b.name}
+	s.write("INSERT INTO " + b.table + " (" + strings.Join(b.columns, ", ") + ") VALUES ")
+
+	placeholders := "(" + strings.Repeat("?, ", len(b.columns)-1) + "?)"
+	for i, row := range b.rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("insert into %s has %d columns but row %d has %d values", b.table, len(b.columns), i+1, len(row))
+		}
+		if i > 0 {
+			s.write(", ")
+		}
+		s.write(placeholders, row...)
+	}
+
+	for _, suffix := range b.suffixes {
+		s.write(" "+suffix.sql, suffix.args...)
+	}
+	s.writeReturning(b.returning)
+
+	return s.build()
+}
+
+// An UpdateBuilder builds an UPDATE statement. Create one with [Update].
+type UpdateBuilder struct {
+	name      string
+	table     string
+	sets      []Predicate
+	where     []Predicate
+	returning []string
+}
+
+// Update starts an UPDATE statement of the table.
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// Named names the statement, so its logs and metrics are labeled with the name rather than the SQL.
+func (b *UpdateBuilder) Named(name string) *UpdateBuilder {
+	b.name = name
+	return b
+}
+
+// Set sets the column to the value.
+func (b *UpdateBuilder) Set(column string, value any) *UpdateBuilder {
+	b.sets = append(b.sets, Cond(column+" = ?", value))
+	return b
+}
+
+// SetIf sets the column to the value when ok is true, e.g. for the fields present in a partial update.
+func (b *UpdateBuilder) SetIf(ok bool, column string, value any) *UpdateBuilder {
+	if !ok {
+		return b
+	}
+
+	return b.Set(column, value)
+}
+
+// SetExpr sets the column to a SQL expression, e.g. SetExpr("updated_at", "NOW()").
+func (b *UpdateBuilder) SetExpr(column, expr string, args ...any) *UpdateBuilder {
+	b.sets = append(b.sets, Cond(column+" = "+expr, args...))
+	return b
+}
+
+// Where adds predicates the updated rows must match. Predicates added by every call are combined with AND.
+func (b *UpdateBuilder) Where(predicates ...Predicate) *UpdateBuilder {
+	b.where = append(b.where, predicates...)
+	return b
+}
+
+// Returning sets the columns of the updated rows returned by the statement.
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning = columns
+	return b
+}
+
+// Build returns the SQL and the arguments of the statement. An update without predicates is an error, so optional
+// filters that are all dropped never update the whole table. Use Where(Cond("TRUE")) to update every row.
+func (b *UpdateBuilder) Build() (string, []any, error) {
+	if len(b.sets) == 0 {
+		return "", nil, fmt.Errorf("update of %s sets no columns", b.table)
+	}
+	if And(b.where...).skip {
+		return "", nil, fmt.Errorf("update of %s has no WHERE clause", b.table)
+	}
+
+	s := statement{name: b.name}
+	s.write("UPDATE " + b.table + " SET ")
+	for i, set := range b.sets {
+		if i > 0 {
+			s.write(", ")
+		}
+		s.write(set.sql, set.args...)
+	}
+	s.writeWhere(b.where)
+	s.writeReturning(b.returning)
+
+	return s.build()
+}
+
+// A DeleteBuilder builds a DELETE statement. Create one with [DeleteFrom].
+type DeleteBuilder struct {
+	name      string
+	table     string
+	where     []Predicate
+	returning []string
+}
+
+// DeleteFrom starts a DELETE statement from the table.
+func DeleteFrom(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+// Named names the statement, so its logs and metrics are labeled with the name rather than the SQL.
+func (b *DeleteBuilder) Named(name string) *DeleteBuilder {
+	b.name = name
+	return b
+}
+
+// Where adds predicates the deleted rows must match. Predicates added by every call are combined with AND.
+func (b *DeleteBuilder) Where(predicates ...Predicate) *DeleteBuilder {
+	b.where = append(b.where, predicates...)
+	return b
+}
+
+// Returning sets the columns of the deleted rows returned by the statement.
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returning = columns
+	return b
+}
+
+// Build returns the SQL and the arguments of the statement. Like an update, a delete without predicates is an
+// error. Use Where(Cond("TRUE")) to delete every row.
+func (b *DeleteBuilder) Build() (string, []any, error) {
+	if And(b.where...).skip {
+		return "", nil, fmt.Errorf("delete from %s has no WHERE clause", b.table)
+	}
+
+	s := statement{name: b.name}
+	s.write("DELETE FROM " + b.table)
+	s.writeWhere(b.where)
+	s.writeReturning(b.returning)
+
+	return s.build()
+}
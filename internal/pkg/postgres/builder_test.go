@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"errors"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"slices"
+	"testing"
+)
+
+func TestNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+		n    int
+	}{
+		{sql: "SELECT 1", want: "SELECT 1"},
+		{sql: "a = ?", want: "a = $1", n: 1},
+		{sql: "a = ? AND b IN (?, ?)", want: "a = $1 AND b IN ($2, $3)", n: 3},
+		{sql: "tags ?? ?", want: "tags ? $1", n: 1},
+		{sql: "tags ??| ? AND a = ?", want: "tags ?| $1 AND a = $2", n: 2},
+		{sql: "???", want: "?$1", n: 1},
+		{sql: "????", want: "??"},
+		{sql: "?", want: "$1", n: 1},
+	}
+
+	for _, tt := range tests {
+		got, n := numberPlaceholders(tt.sql)
+		if got != tt.want || n != tt.n {
+			t.Errorf("numberPlaceholders(%q) = %q, %d, want %q, %d", tt.sql, got, n, tt.want, tt.n)
+		}
+	}
+}
+
+func TestSelectBuilderNumbersPlaceholdersAcrossPredicates(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *SelectBuilder
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "no predicates",
+			builder:  Select("id").From("timelines"),
+			wantSQL:  "SELECT id FROM timelines",
+			wantArgs: nil,
+		},
+		{
+			name: "nested and, or, if and in",
+			builder: Select("id", "title").From("timelines").Where(
+				Cond("user_id = ?", "u_1"),
+				Or(
+					And(Cond("title LIKE ?", "a%"), If(false, Cond("archived = ?", true))),
+					In("id", []string{"tl_1", "tl_2"}),
+				),
+				If(true, Cond("created_at >= ?", 10)),
+			).Limit(20).Offset(40),
+			wantSQL: "SELECT id, title FROM timelines WHERE (user_id = $1) AND ((title LIKE $2) OR (id IN ($3, $4)))" +
+				" AND (created_at >= $5) LIMIT $6 OFFSET $7",
+			wantArgs: []any{"u_1", "a%", "tl_1", "tl_2", 10, 20, 40},
+		},
+		{
+			name:     "dropped predicates",
+			builder:  Select("id").From("timelines").Where(If(false, Cond("a = ?", 1)), And(If(false, Cond("b = ?", 2)))),
+			wantSQL:  "SELECT id FROM timelines",
+			wantArgs: nil,
+		},
+		{
+			name:     "empty in",
+			builder:  Select("id").From("timelines").Where(In("id", []string{}), Cond("user_id = ?", "u_1")),
+			wantSQL:  "SELECT id FROM timelines WHERE (FALSE) AND (user_id = $1)",
+			wantArgs: []any{"u_1"},
+		},
+		{
+			name: "jsonb operator",
+			builder: Select("id").From("events e").Join("JOIN timelines t ON t.id = e.timeline_id AND t.user_id = ?", "u_1").
+				Where(Cond("e.tags ?? ?", "travel"), Cond("e.title = ?", "x")),
+			wantSQL:  "SELECT id FROM events e JOIN timelines t ON t.id = e.timeline_id AND t.user_id = $1 WHERE (e.tags ? $2) AND (e.title = $3)",
+			wantArgs: []any{"u_1", "travel", "x"},
+		},
+		{
+			name:     "named",
+			builder:  Select("id").Named("timelines.List").From("timelines").Where(Cond("id = ?", "tl_1")),
+			wantSQL:  "-- name: timelines.List\nSELECT id FROM timelines WHERE id = $1",
+			wantArgs: []any{"tl_1"},
+		},
+		{
+			name:     "sorted",
+			builder:  Select("id").From("timelines").SortBy("name,-created", SortAllowlist{"name": "title", "created": "created_at"}),
+			wantSQL:  "SELECT id FROM timelines ORDER BY title ASC, created_at DESC",
+			wantArgs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.builder.Build()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestWriteBuildersNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func() (string, []any, error)
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name: "insert",
+			build: InsertInto("timelines").Columns("title", "user_id").Values("a", "u_1").Values("b", "u_1").
+				Suffix("ON CONFLICT (title) DO UPDATE SET title = ?", "c").Returning("id").Build,
+			wantSQL:  "INSERT INTO timelines (title, user_id) VALUES ($1, $2), ($3, $4) ON CONFLICT (title) DO UPDATE SET title = $5 RETURNING id",
+			wantArgs: []any{"a", "u_1", "b", "u_1", "c"},
+		},
+		{
+			name: "update",
+			build: Update("timelines").Set("title", "a").SetIf(false, "user_id", "u_2").SetExpr("updated_at", "NOW() + ? * INTERVAL '1 second'", 5).
+				Where(Cond("id = ?", "tl_1"), Or(Cond("version = ?", 1), Cond("version IS NULL"))).Returning("id", "title").Build,
+			wantSQL:  "UPDATE timelines SET title = $1, updated_at = NOW() + $2 * INTERVAL '1 second' WHERE (id = $3) AND ((version = $4) OR (version IS NULL)) RETURNING id, title",
+			wantArgs: []any{"a", 5, "tl_1", 1},
+		},
+		{
+			name:     "delete",
+			build:    DeleteFrom("timelines").Where(In("id", [2]string{"tl_1", "tl_2"}), If(false, Cond("user_id = ?", "u_1"))).Build,
+			wantSQL:  "DELETE FROM timelines WHERE id IN ($1, $2)",
+			wantArgs: []any{"tl_1", "tl_2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.build()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", sql, tt.wantSQL)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildersRejectInvalidStatements(t *testing.T) {
+	tests := map[string]func() (string, []any, error){
+		"more placeholders than args": Select("id").From("timelines").Where(Cond("a = ? AND b = ?", 1)).Build,
+		"more args than placeholders": Select("id").From("timelines").Where(Cond("a = ?", 1, 2)).Build,
+		"no columns":                  Select().From("timelines").Build,
+		"update without where":        Update("timelines").Set("title", "a").Where(If(false, Cond("id = ?", 1))).Build,
+		"update without sets":         Update("timelines").Where(Cond("id = ?", 1)).Build,
+		"delete without where":        DeleteFrom("timelines").Build,
+		"insert with a missing value": InsertInto("timelines").Columns("title", "user_id").Values("a").Build,
+		"insert without columns":      InsertInto("timelines").Build,
+	}
+
+	for name, build := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := build(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestSelectBuilderRejectsUnknownSort(t *testing.T) {
+	_, _, err := Select("id").From("timelines").SortBy("-user_id", SortAllowlist{"name": "title"}).Build()
+
+	var invalid acerrors.InvalidInputError
+	if !errors.As(err, &invalid) {
+		t.Errorf("expected an invalid input error, got %v", err)
+	}
+}
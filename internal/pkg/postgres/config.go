@@ -27,6 +27,19 @@ type Config struct {
 	User     string `env:"USER"`
 	Password string `env:"PASSWORD"`
 
+	// PasswordFile is the path of a file holding the password, e.g. one mounted by a secrets agent. It is read again
+	// whenever it changes. Takes precedence over [Password] when set.
+	PasswordFile string `env:"PASSWORD_FILE"`
+	// PasswordCommand is a command printing the password, e.g. one generating a short-lived token. It is split on
+	// whitespace and not run by a shell. Takes precedence over [PasswordFile] and [Password] when set.
+	PasswordCommand string `env:"PASSWORD_COMMAND"`
+	// PasswordCommandTTL is how long the output of [PasswordCommand] is reused. Defaults to 5 minutes when not set.
+	PasswordCommandTTL time.Duration `env:"PASSWORD_COMMAND_TTL"`
+	// CredentialRefreshInterval is how often the credentials are checked for changes, when they come from
+	// [PasswordFile] or [PasswordCommand]. Connections opened with old credentials are then recycled. Defaults to
+	// 1 minute when not set.
+	CredentialRefreshInterval time.Duration `env:"CREDENTIAL_REFRESH_INTERVAL"`
+
 	// ApplicationName is used to identity the application using the database.
 	ApplicationName string `env:"APPLICATION_NAME"`
 
@@ -40,11 +53,52 @@ type Config struct {
 	ConnectionPoolMin int `env:"CONNECTION_POOL_MIN"`
 	// ConnectionRetries is number of maximum number of retries to use when acquiring a new connection.
 	ConnectionRetries int `env:"CONNECTION_RETRIES"`
-	// ConnectionRetryWaitTime is number of seconds to wait between connection retries.
+	// ConnectionRetryWaitTime is number of seconds to wait before the first retry of connecting to the database.
+	// Each later retry waits twice as long, up to [ConnectionRetryMaxBackoff]. Defaults to 1 second when not set.
 	ConnectionRetryWaitTime int `env:"CONNECTION_RETRY_WAIT_TIME"`
+	// ConnectionRetryMaxBackoff caps the wait between retries of connecting to the database. Defaults to 30 seconds
+	// when not set.
+	ConnectionRetryMaxBackoff time.Duration `env:"CONNECTION_RETRY_MAX_BACKOFF"`
+	// ConnectMaxElapsedTime is how long connecting to the database is retried for. Connecting is retried until the
+	// context is done when not set.
+	ConnectMaxElapsedTime time.Duration `env:"CONNECT_MAX_ELAPSED_TIME"`
+
+	// TxIsolationLevel is the default isolation level of transactions run by [DB.Transaction], e.g. "serializable".
+	// Defaults to the database default when not set.
+	TxIsolationLevel string `env:"TX_ISOLATION_LEVEL"`
+	// TxMaxAttempts is the maximum number of times a transaction is run when it fails with a retryable error.
+	TxMaxAttempts int `env:"TX_MAX_ATTEMPTS"`
+	// TxRetryInitialBackoff is the wait before the first retry of a transaction.
+	TxRetryInitialBackoff time.Duration `env:"TX_RETRY_INITIAL_BACKOFF"`
+	// TxRetryMaxBackoff is the maximum wait between retries of a transaction.
+	TxRetryMaxBackoff time.Duration `env:"TX_RETRY_MAX_BACKOFF"`
+
+	// ReplicaHosts are the hosts of read replicas, optionally with a port, e.g. "replica-1:5432". Read-only
+	// transactions are routed to the healthy replicas, falling back to the primary when none are healthy.
+	ReplicaHosts []string `env:"REPLICA_HOSTS"`
+	// ReplicaSelection is how a replica is chosen for a read-only transaction, "round_robin" or "least_connections".
+	// Defaults to "round_robin" when not set.
+	ReplicaSelection string `env:"REPLICA_SELECTION"`
+	// ReplicaHealthCheckInterval is how often the health and lag of the replicas is checked. Defaults to 5 seconds
+	// when not set.
+	ReplicaHealthCheckInterval time.Duration `env:"REPLICA_HEALTH_CHECK_INTERVAL"`
+	// ReplicaMaxLag is the replication lag after which a replica is considered unhealthy. Lag is not checked when
+	// not set.
+	ReplicaMaxLag time.Duration `env:"REPLICA_MAX_LAG"`
+
+	// EnableNestedTx makes beginning a context transaction, when one is already on the context, create a savepoint
+	// that can be rolled back independently of the outer transaction. See [ContextWithNestedTx].
+	EnableNestedTx bool `env:"ENABLE_NESTED_TX"`
 
 	// EnableDBLogging enables verbose logging from the database.
 	EnableDBLogging bool `env:"ENABLE_DB_LOGGING"`
+	// SlowQueryThreshold is the duration after which a query is logged as slow when [EnableDBLogging] is set.
+	// Defaults to 500 milliseconds when not set.
+	SlowQueryThreshold time.Duration `env:"SLOW_QUERY_THRESHOLD"`
+	// ExplainSlowQueries logs the plan of slow queries, captured in a savepoint that is rolled back when the query ran
+	// in a transaction. SELECT queries that neither lock rows nor call functions are run again with EXPLAIN ANALYZE,
+	// so this should not be enabled in production.
+	ExplainSlowQueries bool `env:"EXPLAIN_SLOW_QUERIES"`
 
 	// DialTimeout is the number of seconds to wait before timing out the connection request.
 	DialTimeout int `env:"DIAL_TIMEOUT"`
@@ -74,6 +128,12 @@ type Config struct {
 
 	// Subsystem is used when creating metrics. Defaults to database when not set.
 	Subsystem string `env:"SUBSYSTEM"`
+
+	// MigrateOnConnect applies the pending migrations when connecting to the database.
+	MigrateOnConnect bool `env:"MIGRATE_ON_CONNECT"`
+
+	// StatsInterval is how often the connection pool statistics are collected. Defaults to 15 seconds when not set.
+	StatsInterval time.Duration `env:"STATS_INTERVAL"`
 }
 
 func (c Config) URL() string {
@@ -98,9 +158,7 @@ func (c Config) ToPostgresConnConfig(logger slog.Logger) (*pgx.ConnConfig, error
 		return nil, err
 	}
 
-	if c.EnableDBLogging {
-		// TODO
-	}
+	cc.Tracer = newQueryTracer(logger, c)
 
 	cc.DialFunc = c.determineDialer()
 
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/puddle/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConnectRetryInitialBackoff = time.Second
+	defaultConnectRetryMaxBackoff     = 30 * time.Second
+	defaultAcquireRetryInitialBackoff = 100 * time.Millisecond
+	defaultAcquireRetryMaxBackoff     = 2 * time.Second
+	retryJitter                       = 0.5
+)
+
+// SQLSTATE codes and classes of connection failures, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	CodeInvalidCatalogName = "3D000"
+
+	classInvalidAuthorization = "28"
+)
+
+// ConnectRetryPolicy returns how connecting to the database is retried, from [Config.ConnectionRetryWaitTime],
+// [Config.ConnectionRetryMaxBackoff] and [Config.ConnectMaxElapsedTime]. Only [IsRetryableConnectError] errors
+// are retried.
+func (c Config) ConnectRetryPolicy() retry.Policy {
+	p := retry.Policy{
+		InitialBackoff: defaultConnectRetryInitialBackoff,
+		MaxBackoff:     defaultConnectRetryMaxBackoff,
+		Jitter:         retryJitter,
+		MaxElapsedTime: c.ConnectMaxElapsedTime,
+		Retryable:      IsRetryableConnectError,
+	}
+
+	if c.ConnectionRetryWaitTime > 0 {
+		p.InitialBackoff = time.Duration(c.ConnectionRetryWaitTime) * time.Second
+	}
+
+	if c.ConnectionRetryMaxBackoff > 0 {
+		p.MaxBackoff = c.ConnectionRetryMaxBackoff
+	}
+
+	return p
+}
+
+// AcquireRetryPolicy returns how acquiring a connection from the pool is retried, up to [Config.ConnectionRetries]
+// times after the first attempt. Only [IsRetryableConnectError] errors are retried.
+func (c Config) AcquireRetryPolicy() retry.Policy {
+	return retry.Policy{
+		InitialBackoff: defaultAcquireRetryInitialBackoff,
+		MaxBackoff:     defaultAcquireRetryMaxBackoff,
+		Jitter:         retryJitter,
+		MaxAttempts:    max(c.ConnectionRetries, 0) + 1,
+		Retryable:      IsRetryableConnectError,
+	}
+}
+
+// IsRetryableConnectError returns true when err is a failure to connect to the database that may go away by itself,
+// e.g. the server being unreachable or starting up. Authentication failures and unknown databases are not retryable,
+// since they are configuration problems, and neither are canceled or expired contexts, nor acquiring from a closed
+// pool, which never reopens.
+func IsRetryableConnectError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, puddle.ErrClosedPool) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return !strings.HasPrefix(pgErr.Code, classInvalidAuthorization) && pgErr.Code != CodeInvalidCatalogName
+	}
+
+	return true
+}
+
+// Outcomes of the attempts counted by [RegisterConnectionAttempts].
+const (
+	AttemptOutcomeSuccess = "success"
+	AttemptOutcomeRetry   = "retry"
+	AttemptOutcomeFailure = "failure"
+)
+
+// RegisterConnectionAttempts registers the counter of attempts to connect to the database and acquire connections
+// with the default prometheus registry, labeled by operation, e.g. "connect", and outcome, one of "success", "retry"
+// or "failure". The counter registered by an earlier database is reused.
+func RegisterConnectionAttempts(logger slog.Logger, subsystem string) *prometheus.CounterVec {
+	if acstrings.IsBlank(subsystem) {
+		subsystem = defaultSubsystem
+	}
+
+	attempts := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: subsystem,
+		Name:      "connection_attempts_total",
+		Help:      "Number of attempts to connect to the database and acquire connections, by operation and outcome.",
+	}, []string{"operation", "outcome"})
+
+	if err := prometheus.Register(attempts); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing
+			}
+		}
+		logger.WithError(err).WarnContext(context.Background(), "Problem registering connection attempts with prometheus")
+	}
+
+	return attempts
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPasswordCommandTTL     = 5 * time.Minute
+	defaultPasswordCommandTimeout = 30 * time.Second
+)
+
+// Credentials authenticate connections to the database.
+type Credentials struct {
+	// User is the role connecting. The user of the [Config] is kept when it is blank.
+	User     string
+	Password string
+}
+
+// A CredentialProvider returns the credentials of new connections to the database. It is consulted before every
+// connection is opened, so implementations should cache credentials that are costly to get, and it is consulted
+// periodically to find out when the credentials change, so the connections opened with the old ones can be recycled.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a func to a [CredentialProvider].
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials are credentials that never change, such as the ones of the [Config].
+type StaticCredentials Credentials
+
+func (c StaticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// CredentialProvider returns the provider of the credentials of the config: the output of [Config.PasswordCommand]
+// when it is set, else the contents of [Config.PasswordFile] when it is set, else the static [Config.Password].
+// The user is always [Config.User].
+func (c Config) CredentialProvider() CredentialProvider {
+	switch {
+	case acstrings.IsNotBlank(c.PasswordCommand):
+		ttl := defaultPasswordCommandTTL
+		if c.PasswordCommandTTL > 0 {
+			ttl = c.PasswordCommandTTL
+		}
+
+		return NewCommandCredentialProvider(c.User, strings.Fields(c.PasswordCommand), ttl)
+	case acstrings.IsNotBlank(c.PasswordFile):
+		return NewFileCredentialProvider(c.User, c.PasswordFile)
+	default:
+		return StaticCredentials{User: c.User, Password: c.Password}
+	}
+}
+
+// A FileCredentialProvider reads the password from a file, e.g. one mounted by a secrets agent, ignoring leading and
+// trailing whitespace. The file is read again whenever its modification time or size changes, so a rotated password
+// is picked up without restarting.
+type FileCredentialProvider struct {
+	user string
+	path string
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	password string
+}
+
+func NewFileCredentialProvider(user, path string) *FileCredentialProvider {
+	return &FileCredentialProvider{user: user, path: path}
+}
+
+func (p *FileCredentialProvider) Credentials(context.Context) (Credentials, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("could not read password file: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !info.ModTime().Equal(p.modTime) || info.Size() != p.size {
+		contents, err := os.ReadFile(p.path)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("could not read password file: %w", err)
+		}
+
+		password := strings.TrimSpace(string(contents))
+		if password == "" {
+			return Credentials{}, fmt.Errorf("password file '%s' is empty", p.path)
+		}
+
+		p.password, p.modTime, p.size = password, info.ModTime(), info.Size()
+	}
+
+	return Credentials{User: p.user, Password: p.password}, nil
+}
+
+// A CommandCredentialProvider runs a command printing the password, e.g. one generating a short-lived token, and
+// reuses its output, ignoring leading and trailing whitespace, for the TTL. The command is not run by a shell.
+type CommandCredentialProvider struct {
+	user    string
+	command []string
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+	password  string
+}
+
+func NewCommandCredentialProvider(user string, command []string, ttl time.Duration) *CommandCredentialProvider {
+	return &CommandCredentialProvider{user: user, command: command, ttl: ttl}
+}
+
+func (p *CommandCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.password == "" || time.Since(p.fetchedAt) >= p.ttl {
+		password, err := p.run(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		p.password, p.fetchedAt = password, time.Now()
+	}
+
+	return Credentials{User: p.user, Password: p.password}, nil
+}
+
+func (p *CommandCredentialProvider) run(ctx context.Context) (string, error) {
+	if len(p.command) == 0 {
+		return "", errors.New("no password command")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultPasswordCommandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return "", fmt.Errorf("password command '%s' failed: %w: %s", p.command[0], err, output)
+		}
+		return "", fmt.Errorf("password command '%s' failed: %w", p.command[0], err)
+	}
+
+	password := strings.TrimSpace(stdout.String())
+	if password == "" {
+		return "", fmt.Errorf("password command '%s' printed no password", p.command[0])
+	}
+
+	return password, nil
+}
@@ -4,17 +4,29 @@ import (
 	"context"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
 type DBStats struct {
-	MaxConnections          int
-	CurrentConnections      int
-	AvailableConnections    int
-	AcquiredConnections     int
-	EmptyAcquiredCount      int
-	NewConnectionsTotal     int
-	MaxLifetimeDestroyTotal int
-	MaxIdleDestroyTotal     int
+	MaxConnections          int `json:"max_connections"`
+	CurrentConnections      int `json:"current_connections"`
+	AvailableConnections    int `json:"available_connections"`
+	AcquiredConnections     int `json:"acquired_connections"`
+	ConstructingConnections int `json:"constructing_connections"`
+	EmptyAcquiredCount      int `json:"empty_acquired_count"`
+	NewConnectionsTotal     int `json:"new_connections_total"`
+	MaxLifetimeDestroyTotal int `json:"max_lifetime_destroy_total"`
+	MaxIdleDestroyTotal     int `json:"max_idle_destroy_total"`
+	// AcquireCount is the cumulative count of successful connection acquires.
+	AcquireCount int `json:"acquire_count"`
+	// AcquireDuration is the cumulative time spent acquiring connections, including waiting for one to be available.
+	AcquireDuration time.Duration `json:"acquire_duration"`
+	// CanceledAcquireCount is the cumulative count of acquires canceled by their context.
+	CanceledAcquireCount int `json:"canceled_acquire_count"`
+	// CollectedAt is when the stats were collected, the zero value if they have not been collected yet.
+	CollectedAt time.Time `json:"collected_at"`
+	// Replicas are the stats of the pool of each read replica, by host.
+	Replicas map[string]DBStats `json:"replicas,omitempty"`
 }
 
 type BatchStatement struct {
@@ -22,16 +34,47 @@ type BatchStatement struct {
 	Arguments []any
 }
 
+// A BatchResult is the outcome of a single [BatchStatement] sent as part of a batch.
+type BatchResult struct {
+	// RowsAffected is the number of rows inserted, updated, deleted or selected by the statement.
+	RowsAffected int64
+	// Rows are the rows returned by the statement, keyed by column name.
+	Rows []map[string]any
+	// Err is the error returned by the statement, if any.
+	Err error
+}
+
+// A Notification is a message sent on a channel with NOTIFY. See [DB.Subscribe].
+type Notification struct {
+	Channel string
+	Payload string
+	// PID is the process ID of the database session that sent the notification.
+	PID uint32
+}
+
 type DB interface {
 	Acquire(context.Context) (*pgxpool.Conn, error)
 	Connect(context.Context) error
 	Run(context.Context, func(*pgxpool.Conn) error) error
-	Transaction(context.Context, func(pgx.Tx) error) error
+	// Transaction runs the func in a transaction, re-running it when it fails with a retryable error.
+	// When the context already carries a transaction, the func joins it instead of starting a new one.
+	Transaction(context.Context, func(pgx.Tx) error, ...TxOption) error
+	// TransactionContext is like Transaction, but the func receives a context carrying the transaction.
+	TransactionContext(context.Context, func(context.Context) error, ...TxOption) error
 	ContextWithTx(context.Context, pgx.Tx) context.Context
 	BeginContextTx(context.Context) (context.Context, bool, error)
+	// BeginContextReadOnlyTx is like BeginContextTx, but a new transaction is read-only. A transaction already on the
+	// context is joined, or nested in a savepoint, whatever its access mode, so the savepoint may be writable.
 	BeginContextReadOnlyTx(context.Context) (context.Context, bool, error)
 	CloseContextTx(context.Context, error) error
+	// CurrentLSN returns the current write-ahead log position of the primary. See [ContextWithMinLSN].
+	CurrentLSN(context.Context) (LSN, error)
 	NewBatch([]BatchStatement) *pgx.Batch
+	// Subscribe returns the notifications sent on the channel, until the context is done or the database is shut
+	// down, when the returned channel is closed. Notifications are dropped for subscribers that fall behind.
+	Subscribe(ctx context.Context, channel string) (<-chan Notification, error)
+	// Notify sends the payload on the channel in the context transaction, so subscribers receive it on commit.
+	Notify(ctx context.Context, channel, payload string) error
 	Shutdown(context.Context) error
 	SecurityString() [32]byte
 	Stats() DBStats
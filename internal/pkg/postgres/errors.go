@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"regexp"
+	"strings"
+)
+
+// SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	CodeNotNullViolation     = "23502"
+	CodeForeignKeyViolation  = "23503"
+	CodeUniqueViolation      = "23505"
+	CodeCheckViolation       = "23514"
+	CodeExclusionViolation   = "23P01"
+	CodeSerializationFailure = "40001"
+	CodeDeadlockDetected     = "40P01"
+	CodeQueryCanceled        = "57014"
+
+	classDataException = "22"
+)
+
+const (
+	detailCode       = "code"
+	detailTable      = "table"
+	detailColumn     = "column"
+	detailConstraint = "constraint"
+)
+
+// keyColumnsPattern extracts the column list from a postgres error detail, e.g. `Key (user_id)=(abc) already exists.`
+var keyColumnsPattern = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// An Error is a database error that has been translated into one of the error kinds in [acerrors].
+// It keeps the original error as its cause, and exposes the names of the offending table, column
+// and constraint through [acerrors.Detailer] so an API can report which field caused the failure.
+type Error struct {
+	// Kind is the [acerrors] error kind, e.g. [acerrors.ConflictError].
+	Kind error
+	// Code is the SQLSTATE reported by postgres, if any.
+	Code       string
+	Table      string
+	Column     string
+	Constraint string
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error()
+}
+
+// Unwrap allows [errors.Is] and [errors.As] to match both the error kind and the original cause.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.cause}
+}
+
+func (e *Error) StatusCode() int {
+	var statusCoder acerrors.StatusCoder
+	if errors.As(e.Kind, &statusCoder) {
+		return statusCoder.StatusCode()
+	}
+
+	return 0
+}
+
+func (e *Error) Details() map[string]string {
+	details := map[string]string{}
+	addDetail(details, detailCode, e.Code)
+	addDetail(details, detailTable, e.Table)
+	addDetail(details, detailColumn, e.Column)
+	addDetail(details, detailConstraint, e.Constraint)
+
+	return details
+}
+
+func addDetail(details map[string]string, key, value string) {
+	if acstrings.IsNotBlank(value) {
+		details[key] = value
+	}
+}
+
+// TranslateError maps errors returned by pgx into the error kinds found in [acerrors]. Errors that
+// do not have a meaningful translation are returned unchanged, as is a nil error.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var translated *Error
+	if errors.As(err, &translated) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: acerrors.NewNotFoundError("record not found"), cause: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return translatePgError(pgErr, err)
+	}
+
+	if pgconn.Timeout(err) {
+		return &Error{Kind: acerrors.NewTimeoutError("database operation timed out"), cause: err}
+	}
+
+	return err
+}
+
+func translatePgError(pgErr *pgconn.PgError, cause error) error {
+	e := &Error{
+		Code:       pgErr.Code,
+		Table:      pgErr.TableName,
+		Column:     determineColumn(pgErr),
+		Constraint: pgErr.ConstraintName,
+		cause:      cause,
+	}
+
+	switch {
+	case pgErr.Code == CodeUniqueViolation, pgErr.Code == CodeExclusionViolation:
+		e.Kind = acerrors.NewConflictErrorf("%s already exists", e.subject())
+	case pgErr.Code == CodeForeignKeyViolation:
+		e.Kind = translateForeignKeyViolation(pgErr, e)
+	case pgErr.Code == CodeNotNullViolation:
+		e.Kind = acerrors.NewInvalidInputErrorf("%s is required", e.subject())
+	case pgErr.Code == CodeCheckViolation:
+		e.Kind = acerrors.NewInvalidInputErrorf("%s is not valid", e.subject())
+	case strings.HasPrefix(pgErr.Code, classDataException):
+		e.Kind = acerrors.NewInvalidInputErrorf("invalid value: %s", pgErr.Message)
+	case pgErr.Code == CodeSerializationFailure, pgErr.Code == CodeDeadlockDetected:
+		e.Kind = acerrors.NewRetryableError("concurrent update detected, please retry")
+	case pgErr.Code == CodeQueryCanceled:
+		e.Kind = acerrors.NewTimeoutError("database operation timed out")
+	default:
+		return cause
+	}
+
+	return e
+}
+
+// translateForeignKeyViolation distinguishes between a write that references a row that does not exist,
+// and a delete (or update) of a row that is still referenced by another table.
+func translateForeignKeyViolation(pgErr *pgconn.PgError, e *Error) error {
+	if strings.Contains(pgErr.Detail, "is still referenced") {
+		return acerrors.NewConflictErrorf("%s is still referenced", e.subject())
+	}
+
+	return acerrors.NewNotFoundErrorf("referenced %s does not exist", e.subject())
+}
+
+// subject returns the most specific, human-readable name for the thing that caused the error.
+func (e *Error) subject() string {
+	switch {
+	case acstrings.IsNotBlank(e.Column):
+		return e.Column
+	case acstrings.IsNotBlank(e.Constraint):
+		return e.Constraint
+	case acstrings.IsNotBlank(e.Table):
+		return e.Table
+	default:
+		return "record"
+	}
+}
+
+func determineColumn(pgErr *pgconn.PgError) string {
+	if acstrings.IsNotBlank(pgErr.ColumnName) {
+		return pgErr.ColumnName
+	}
+
+	matches := keyColumnsPattern.FindStringSubmatch(pgErr.Detail)
+	if len(matches) < 2 {
+		return ""
+	}
+
+	return strings.ReplaceAll(matches[1], " ", "")
+}
+
+// IsRetryable returns true when err represents a transient failure, e.g. a serialization failure or deadlock,
+// where re-running the whole transaction may succeed.
+func IsRetryable(err error) bool {
+	return acerrors.HasRetryableError(TranslateError(err))
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
+	"time"
+)
+
+const (
+	// NotifyChannel is the channel notified when a job is enqueued, so idle workers claim it without waiting for
+	// their next poll.
+	NotifyChannel = "jobs"
+
+	defaultMaxAttempts = 5
+
+	enqueueQuery = `INSERT INTO jobs (kind, payload, priority, unique_key, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+		ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND completed_at IS NULL AND failed_at IS NULL DO NOTHING
+		RETURNING id`
+)
+
+// A Job is a unit of background work claimed by a worker of a [Pool].
+type Job struct {
+	ID          int64           `db:"id"`
+	Kind        string          `db:"kind"`
+	Payload     json.RawMessage `db:"payload"`
+	Priority    int             `db:"priority"`
+	UniqueKey   *string         `db:"unique_key"`
+	Attempts    int             `db:"attempts"`
+	MaxAttempts int             `db:"max_attempts"`
+	RunAt       time.Time       `db:"run_at"`
+	CreatedAt   time.Time       `db:"created_at"`
+}
+
+// Decode decodes the JSON payload of the job into v.
+func (j Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("could not decode payload of %s job %d: %w", j.Kind, j.ID, err)
+	}
+
+	return nil
+}
+
+type enqueueOptions struct {
+	priority    int
+	uniqueKey   *string
+	maxAttempts int
+	runAt       *time.Time
+}
+
+// An EnqueueOption configures a job enqueued with [Enqueue].
+type EnqueueOption func(*enqueueOptions)
+
+// WithPriority sets the priority of the job. Due jobs with a higher priority are claimed first. Defaults to 0.
+func WithPriority(priority int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = priority
+	}
+}
+
+// WithRunAt schedules the job to run no earlier than runAt. Defaults to right away.
+func WithRunAt(runAt time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = &runAt
+	}
+}
+
+// WithMaxAttempts sets how many times the job is attempted before it is marked as failed. Defaults to 5.
+func WithMaxAttempts(n int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithUniqueKey makes enqueueing the job a no-op while an unfinished job with the same key exists.
+func WithUniqueKey(key string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = &key
+	}
+}
+
+// Enqueue writes a job of the kind, with the payload encoded as JSON, in the context transaction. The job is only
+// claimed by a worker once the transaction commits. The returned bool is false, and the ID 0, when the job was not
+// enqueued because an unfinished job has the same unique key. See [WithUniqueKey].
+func Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (int64, bool, error) {
+	o := enqueueOptions{maxAttempts: defaultMaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not encode payload of %s job: %w", kind, err)
+	}
+
+	id, enqueued, err := pgx.ScanOneContext[int64](ctx, enqueueQuery, kind, encoded, o.priority, o.uniqueKey, o.maxAttempts, o.runAt)
+	if err != nil || !enqueued {
+		return 0, false, err
+	}
+
+	if _, err := pgx.ExecInsertContext(ctx, "SELECT pg_notify($1, $2)", NotifyChannel, kind); err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
@@ -0,0 +1,325 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acsync "github.com/zhughes3/go-accelerate/pkg/sync"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers           = 4
+	defaultPollInterval      = 5 * time.Second
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultInitialBackoff    = 5 * time.Second
+	defaultMaxBackoff        = time.Hour
+	// retryJitter spreads the retries of jobs that failed together, see [retry.Policy.Jitter]
+	retryJitter = 0.5
+
+	labelJobID    = "jobId"
+	labelKind     = "kind"
+	labelAttempts = "attempts"
+
+	// failExhaustedQuery fails the jobs whose last attempt never reported an outcome, e.g. because the handler
+	// crashed or hung its worker, once their visibility timeout passes, since they have no attempts left.
+	failExhaustedQuery = `UPDATE jobs SET failed_at = NOW(), locked_until = NULL,
+			last_error = COALESCE(last_error, 'the last attempt did not finish before its visibility timeout')
+		WHERE completed_at IS NULL AND failed_at IS NULL AND attempts >= max_attempts
+			AND locked_until < NOW() AND kind = ANY($1)`
+	// claimQuery locks the next due job, skipping those being claimed by other workers, and hides it from other
+	// workers until the visibility timeout passes. A job whose worker crashed becomes visible again at that point,
+	// as long as it has attempts left.
+	claimQuery = `UPDATE jobs SET attempts = attempts + 1, locked_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE completed_at IS NULL AND failed_at IS NULL AND run_at <= NOW() AND attempts < max_attempts
+				AND (locked_until IS NULL OR locked_until < NOW()) AND kind = ANY($1)
+			ORDER BY priority DESC, run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, payload, priority, unique_key, attempts, max_attempts, run_at, created_at`
+	// The outcome of a job is only recorded by the worker of its latest claim, which incremented its attempts, so a
+	// worker finishing after its visibility timeout cannot overwrite the state of the job claimed by another worker.
+	completeQuery = `UPDATE jobs SET completed_at = NOW(), locked_until = NULL, last_error = NULL
+		WHERE id = $1 AND attempts = $2`
+	retryQuery = `UPDATE jobs SET run_at = NOW() + $4 * INTERVAL '1 millisecond', locked_until = NULL, last_error = $3
+		WHERE id = $1 AND attempts = $2`
+	failQuery = `UPDATE jobs SET failed_at = NOW(), locked_until = NULL, last_error = $3 WHERE id = $1 AND attempts = $2`
+	// releaseQuery gives back the claim of a job canceled by shutdown, without counting its attempt, so it is run
+	// again right away by the next worker.
+	releaseQuery = `UPDATE jobs SET attempts = attempts - 1, locked_until = NULL WHERE id = $1 AND attempts = $2`
+)
+
+// Config configures the workers of a [Pool].
+type Config struct {
+	// Workers is the number of jobs run concurrently. Defaults to 4 when not set.
+	Workers int `env:"WORKERS"`
+	// PollInterval is how often idle workers look for due jobs. Defaults to 5 seconds when not set.
+	PollInterval time.Duration `env:"POLL_INTERVAL"`
+	// VisibilityTimeout is how long a claimed job is hidden from other workers, and the deadline of the job.
+	// Defaults to 5 minutes when not set.
+	VisibilityTimeout time.Duration `env:"VISIBILITY_TIMEOUT"`
+	// RetryInitialBackoff is the wait before the first retry of a failed job. Defaults to 5 seconds when not set.
+	RetryInitialBackoff time.Duration `env:"RETRY_INITIAL_BACKOFF"`
+	// RetryMaxBackoff is the maximum wait between retries of a failed job. Defaults to 1 hour when not set.
+	RetryMaxBackoff time.Duration `env:"RETRY_MAX_BACKOFF"`
+}
+
+// A Handler runs a job. A job is retried with backoff when its handler returns an error, until it runs out of
+// attempts. Handlers may run more than once for the same job, e.g. when a worker crashes, so they should be idempotent.
+type Handler func(context.Context, Job) error
+
+// A Pool of workers runs the jobs of the kinds it has handlers for. Pools on several replicas can run at the same
+// time, since each job is claimed by a single worker.
+type Pool struct {
+	logger   slog.Logger
+	db       postgres.DB
+	config   Config
+	handlers map[string]Handler
+
+	state *acsync.StateMachine
+	bgWG  sync.WaitGroup
+	stop  chan any
+	// cancel cancels the context of the jobs being run, so shutdown does not wait for their visibility timeout
+	cancel context.CancelFunc
+	// wake wakes idle workers when a job is enqueued
+	wake chan any
+}
+
+// NewPool creates a [Pool] with the config, filling in the defaults for unset values.
+func NewPool(logger slog.Logger, db postgres.DB, config Config) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if config.RetryInitialBackoff <= 0 {
+		config.RetryInitialBackoff = defaultInitialBackoff
+	}
+	if config.RetryMaxBackoff <= 0 {
+		config.RetryMaxBackoff = defaultMaxBackoff
+	}
+
+	return &Pool{
+		logger:   logger,
+		db:       db,
+		config:   config,
+		handlers: map[string]Handler{},
+		stop:     make(chan any),
+		wake:     make(chan any, config.Workers),
+		state: acsync.NewStateMachineBuilder(logger).
+			WithComponentName("job_pool").
+			WithIgnoreAlreadyAtEndError(true).
+			Build(),
+	}
+}
+
+// Register sets the handler of the jobs of the kind. Handlers must be registered before the pool is started.
+func (p *Pool) Register(kind string, h Handler) *Pool {
+	p.handlers[kind] = h
+	return p
+}
+
+// Start runs the workers in background goroutines, until the pool is shut down.
+func (p *Pool) Start(ctx context.Context) error {
+	return p.state.Start(ctx, func() error {
+		if len(p.handlers) == 0 {
+			p.logger.InfoContext(ctx, "No job handlers registered; not starting workers")
+			return nil
+		}
+
+		// The subscription and the jobs end when the pool is shut down, rather than with the start context.
+		poolCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		p.cancel = cancel
+
+		notifications, err := p.db.Subscribe(poolCtx, NotifyChannel)
+		if err != nil {
+			p.logger.WithError(err).WarnContext(ctx, "Problem subscribing to job notifications; polling only")
+		}
+
+		p.bgWG.Add(1)
+		go func() {
+			defer p.bgWG.Done()
+			defer cancel()
+
+			for {
+				select {
+				case <-p.stop:
+					return
+				case notification, ok := <-notifications:
+					if !ok {
+						notifications = nil
+						continue
+					}
+					if _, handled := p.handlers[notification.Payload]; handled {
+						p.wakeWorker()
+					}
+				}
+			}
+		}()
+
+		kinds := slices.Collect(maps.Keys(p.handlers))
+		for i := 0; i < p.config.Workers; i++ {
+			p.bgWG.Add(1)
+			go p.work(poolCtx, kinds)
+		}
+
+		p.logger.InfoContextf(ctx, "Started job workers; workers: %d", p.config.Workers)
+
+		return nil
+	})
+}
+
+// Shutdown stops claiming jobs, canceling the context of the jobs being run and waiting for their handlers to return.
+// Jobs whose handlers fail because of the cancellation are released without counting the attempt, so they are run
+// again, even on their last attempt.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	return p.state.Shutdown(ctx, func() error {
+		p.logger.InfoContext(ctx, "Shutting down the job workers...")
+		close(p.stop)
+		if p.cancel != nil {
+			p.cancel()
+		}
+		p.bgWG.Wait()
+		p.logger.InfoContext(ctx, "Shutting down the job workers...complete")
+
+		return nil
+	})
+}
+
+func (p *Pool) wakeWorker() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work claims and runs jobs until the pool is shut down, waiting for a poll or a wake up whenever no job is due.
+func (p *Pool) work(ctx context.Context, kinds []string) {
+	defer p.bgWG.Done()
+
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		ran, err := p.runNext(ctx, kinds)
+		if err != nil && ctx.Err() == nil {
+			p.logger.WithError(err).WarnContext(ctx, "Problem running job")
+		}
+
+		if ran {
+			continue
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// runNext claims the next due job and runs it, returning false when no job is due.
+func (p *Pool) runNext(ctx context.Context, kinds []string) (bool, error) {
+	job, claimed, err := p.claim(ctx, kinds)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	logger := p.logger.With(labelJobID, job.ID).With(labelKind, job.Kind).With(labelAttempts, job.Attempts)
+
+	begin := time.Now()
+	runErr := p.run(ctx, job)
+
+	// The outcome is recorded even when the pool is shutting down, so a canceled job is run again without waiting
+	// for its visibility timeout.
+	canceled := ctx.Err() != nil
+	return true, p.db.TransactionContext(context.WithoutCancel(ctx), func(ctx context.Context) error {
+		var (
+			recorded bool
+			err      error
+		)
+
+		switch {
+		case runErr == nil:
+			logger.WithDur(time.Since(begin)).DebugContext(ctx, "Completed job")
+			recorded, err = pgx.ExecUpdateContext(ctx, completeQuery, job.ID, job.Attempts)
+		case canceled:
+			logger.WithError(runErr).InfoContext(ctx, "Job canceled by shutdown; releasing it")
+			recorded, err = pgx.ExecUpdateContext(ctx, releaseQuery, job.ID, job.Attempts)
+		case job.Attempts >= job.MaxAttempts:
+			logger.WithError(runErr).ErrorContext(ctx, "Job failed and has no attempts left")
+			recorded, err = pgx.ExecUpdateContext(ctx, failQuery, job.ID, job.Attempts, runErr.Error())
+		default:
+			backoff := p.retryPolicy().Backoff(job.Attempts)
+			logger.WithError(runErr).WithDur(backoff).WarnContext(ctx, "Job failed; retrying")
+			recorded, err = pgx.ExecUpdateContext(ctx, retryQuery, job.ID, job.Attempts, runErr.Error(), backoff.Milliseconds())
+		}
+
+		if err == nil && !recorded {
+			logger.WarnContext(ctx, "Job was claimed again after its visibility timeout; its outcome was not recorded")
+		}
+
+		return err
+	})
+}
+
+func (p *Pool) claim(ctx context.Context, kinds []string) (Job, bool, error) {
+	var (
+		job     Job
+		claimed bool
+	)
+
+	err := p.db.TransactionContext(ctx, func(ctx context.Context) error {
+		if _, err := pgx.ExecUpdateContext(ctx, failExhaustedQuery, kinds); err != nil {
+			return err
+		}
+
+		var err error
+		job, claimed, err = pgx.ScanOneContext[Job](ctx, claimQuery, kinds, p.config.VisibilityTimeout.Milliseconds())
+		return err
+	})
+
+	return job, claimed, err
+}
+
+// run runs the handler of the job before its visibility timeout passes, turning a panic into an error.
+func (p *Pool) run(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.VisibilityTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return p.handlers[job.Kind](ctx, job)
+}
+
+func (p *Pool) retryPolicy() retry.Policy {
+	return retry.Policy{
+		InitialBackoff: p.config.RetryInitialBackoff,
+		MaxBackoff:     p.config.RetryMaxBackoff,
+		Jitter:         retryJitter,
+	}
+}
@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// LockKey hashes the name of an advisory lock into the int64 key postgres identifies advisory locks by.
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// A SessionLock is an advisory lock held by a database session, until it is unlocked or the session ends.
+type SessionLock interface {
+	Unlock(context.Context) error
+}
@@ -0,0 +1,192 @@
+package migrate
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	annotationPrefix         = "-- +goose"
+	annotationUp             = "Up"
+	annotationDown           = "Down"
+	annotationStatementBegin = "StatementBegin"
+	annotationStatementEnd   = "StatementEnd"
+	annotationNoTransaction  = "NO TRANSACTION"
+)
+
+// A Migration is a versioned SQL file in the goose format. The file is named "<version>_<name>.sql" and split
+// into sections by "-- +goose Up" and "-- +goose Down" annotations. Statements end with a semicolon at the end
+// of a line, unless they are wrapped in "-- +goose StatementBegin" and "-- +goose StatementEnd", which is needed
+// for statements like function definitions that contain semicolons.
+type Migration struct {
+	Version int64
+	Name    string
+	Source  string
+
+	UpStatements   []string
+	DownStatements []string
+	// NoTransaction is set by a "-- +goose NO TRANSACTION" annotation, for statements that cannot run in a
+	// transaction, e.g. CREATE INDEX CONCURRENTLY.
+	NoTransaction bool
+}
+
+// LoadMigrations parses the goose migration files in dir of fsys, ordered by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations directory '%s': %w", dir, err)
+	}
+
+	var migrations []Migration
+	versions := map[int64]string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		source := path.Join(dir, entry.Name())
+		version, name, err := parseFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("migrations '%s' and '%s' have the same version %d", other, source, version)
+		}
+		versions[version] = source
+
+		content, err := fs.ReadFile(fsys, source)
+		if err != nil {
+			return nil, fmt.Errorf("reading migration '%s': %w", source, err)
+		}
+
+		migration, err := parseMigration(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("parsing migration '%s': %w", source, err)
+		}
+		migration.Version = version
+		migration.Name = name
+		migration.Source = source
+
+		migrations = append(migrations, migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// parseFilename parses the version and name from a filename like "20241130211754_initialize.sql".
+func parseFilename(filename string) (int64, string, error) {
+	base := strings.TrimSuffix(filename, path.Ext(filename))
+	rawVersion, name, _ := strings.Cut(base, "_")
+
+	version, err := strconv.ParseInt(rawVersion, 10, 64)
+	if err != nil || version < 1 {
+		return 0, "", fmt.Errorf("migration '%s' does not start with a positive version number", filename)
+	}
+
+	return version, name, nil
+}
+
+func parseMigration(content string) (Migration, error) {
+	var (
+		migration   Migration
+		section     *[]string
+		statement   strings.Builder
+		inStatement bool
+		foundUp     bool
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(statement.String()); section != nil && !onlyComments(s) {
+			*section = append(*section, s)
+		}
+		statement.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if annotation, ok := strings.CutPrefix(trimmed, annotationPrefix); ok {
+			switch strings.TrimSpace(annotation) {
+			case annotationUp:
+				flush()
+				section = &migration.UpStatements
+				foundUp = true
+			case annotationDown:
+				flush()
+				section = &migration.DownStatements
+			case annotationStatementBegin:
+				flush()
+				inStatement = true
+			case annotationStatementEnd:
+				if !inStatement {
+					return Migration{}, fmt.Errorf("'%s %s' without a matching '%s'", annotationPrefix, annotationStatementEnd, annotationStatementBegin)
+				}
+				inStatement = false
+				flush()
+			case annotationNoTransaction:
+				migration.NoTransaction = true
+			default:
+				return Migration{}, fmt.Errorf("unknown annotation '%s'", trimmed)
+			}
+			continue
+		}
+
+		if section == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return Migration{}, fmt.Errorf("statement before the '%s %s' annotation", annotationPrefix, annotationUp)
+			}
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+
+		if !inStatement && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+
+	if inStatement {
+		return Migration{}, fmt.Errorf("'%s %s' without a matching '%s'", annotationPrefix, annotationStatementBegin, annotationStatementEnd)
+	}
+
+	if !foundUp {
+		return Migration{}, fmt.Errorf("missing the '%s %s' annotation", annotationPrefix, annotationUp)
+	}
+
+	flush()
+
+	return migration, nil
+}
+
+// onlyComments reports whether the statement is empty once its comment lines are removed.
+func onlyComments(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+
+	return true
+}
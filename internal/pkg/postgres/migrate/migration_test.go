@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseMigration(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		up            []string
+		down          []string
+		noTransaction bool
+	}{
+		{
+			name: "up and down",
+			content: `-- +goose Up
+CREATE TABLE a (id INT);
+CREATE TABLE b (id INT);
+
+-- +goose Down
+DROP TABLE b;
+DROP TABLE a;
+`,
+			up:   []string{"CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);"},
+			down: []string{"DROP TABLE b;", "DROP TABLE a;"},
+		},
+		{
+			name: "up only",
+			content: `-- +goose Up
+CREATE TABLE a (id INT);
+`,
+			up: []string{"CREATE TABLE a (id INT);"},
+		},
+		{
+			name: "statement spanning lines",
+			content: `-- +goose Up
+CREATE TABLE a (
+    id INT
+);
+`,
+			up: []string{"CREATE TABLE a (\n    id INT\n);"},
+		},
+		{
+			name: "comments",
+			content: `-- the first migration
+-- +goose Up
+-- a table
+CREATE TABLE a (id INT);
+-- nothing else
+-- +goose Down
+`,
+			up: []string{"-- a table\nCREATE TABLE a (id INT);"},
+		},
+		{
+			name: "statement begin and end",
+			content: `-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION f() RETURNS INT AS $$
+BEGIN
+    RETURN 1;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+CREATE TABLE a (id INT);
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION f;
+-- +goose StatementEnd
+`,
+			up: []string{
+				"CREATE FUNCTION f() RETURNS INT AS $$\nBEGIN\n    RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;",
+				"CREATE TABLE a (id INT);",
+			},
+			down: []string{"DROP FUNCTION f;"},
+		},
+		{
+			name: "no transaction",
+			content: `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY a_id ON a (id);
+`,
+			up:            []string{"CREATE INDEX CONCURRENTLY a_id ON a (id);"},
+			noTransaction: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseMigration(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(m.UpStatements, tt.up) {
+				t.Errorf("up statements = %q, want %q", m.UpStatements, tt.up)
+			}
+			if !slices.Equal(m.DownStatements, tt.down) {
+				t.Errorf("down statements = %q, want %q", m.DownStatements, tt.down)
+			}
+			if m.NoTransaction != tt.noTransaction {
+				t.Errorf("no transaction = %t, want %t", m.NoTransaction, tt.noTransaction)
+			}
+		})
+	}
+}
+
+func TestParseMigrationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "missing up", content: "-- +goose Down\nDROP TABLE a;\n"},
+		{name: "statement before up", content: "CREATE TABLE a (id INT);\n-- +goose Up\n"},
+		{name: "end without begin", content: "-- +goose Up\nCREATE TABLE a (id INT);\n-- +goose StatementEnd\n"},
+		{name: "begin without end", content: "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a (id INT);\n"},
+		{name: "unknown annotation", content: "-- +goose Up\n-- +goose Sideways\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMigration(tt.content); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/20241201000000_add_events.sql": {Data: []byte("-- +goose Up\nCREATE TABLE events (id INT);\n")},
+		"migrations/20241130211754_initialize.sql": {Data: []byte("-- +goose Up\nCREATE TABLE users (id INT);\n")},
+		"migrations/README.md":                     {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("loaded %d migrations, want 2", len(migrations))
+	}
+
+	first := migrations[0]
+	if first.Version != 20241130211754 || first.Name != "initialize" || first.Source != "migrations/20241130211754_initialize.sql" {
+		t.Errorf("unexpected first migration: %+v", first)
+	}
+	if migrations[1].Version != 20241201000000 || migrations[1].Name != "add_events" {
+		t.Errorf("unexpected second migration: %+v", migrations[1])
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"migrations/1_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+				"migrations/1_b.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			},
+		},
+		{
+			name: "no version",
+			fsys: fstest.MapFS{
+				"migrations/initialize.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadMigrations(tt.fsys, "migrations"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	"io/fs"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultTableName is the table goose tracks applied versions in, so either tool can manage the same database.
+	DefaultTableName = "goose_db_version"
+	// DefaultDir is the directory of the migrations within the [fs.FS].
+	DefaultDir = "."
+
+	labelVersion   = "version"
+	labelMigration = "migration"
+	labelDryRun    = "dryRun"
+)
+
+// A MigrationStatus is a known migration and when it was applied, the zero value if it is pending.
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time
+}
+
+func (s MigrationStatus) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// A Migrator applies and rolls back goose migrations. Concurrent migrators, e.g. on replicas of a service
+// starting at the same time, are serialized with a session advisory lock.
+type Migrator struct {
+	logger     slog.Logger
+	db         postgres.DB
+	migrations []Migration
+
+	dir       string
+	tableName string
+	dryRun    bool
+}
+
+// An Option configures a [Migrator].
+type Option func(*Migrator)
+
+// WithDir sets the directory of the migrations within the [fs.FS]. Defaults to the root of the [fs.FS].
+func WithDir(dir string) Option {
+	return func(m *Migrator) {
+		m.dir = dir
+	}
+}
+
+// WithTableName sets the table applied versions are tracked in. Defaults to [DefaultTableName].
+func WithTableName(tableName string) Option {
+	return func(m *Migrator) {
+		m.tableName = tableName
+	}
+}
+
+// WithDryRun logs the statements that would be run, without running them.
+func WithDryRun() Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// NewMigrator creates a [Migrator] of the goose migrations in fsys.
+func NewMigrator(logger slog.Logger, db postgres.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	m := Migrator{
+		logger:    logger,
+		db:        db,
+		dir:       DefaultDir,
+		tableName: DefaultTableName,
+	}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	var err error
+	m.migrations, err = LoadMigrations(fsys, m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Up applies all pending migrations, returning the migrations applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if len(m.migrations) == 0 {
+		return nil, nil
+	}
+
+	return m.UpTo(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// UpTo applies the pending migrations up to and including version, returning the migrations applied.
+func (m *Migrator) UpTo(ctx context.Context, version int64) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *pgx.Conn, statuses []MigrationStatus) error {
+		if err := checkMissing(statuses); err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if status.Applied() || status.Version > version {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, status.Migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, status.Migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the most recently applied migration, returning it, or nil when no migration is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var rolledBack *Migration
+
+	err := m.withLock(ctx, func(conn *pgx.Conn, statuses []MigrationStatus) error {
+		for _, status := range slices.Backward(statuses) {
+			if !status.Applied() {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, status.Migration, false); err != nil {
+				return err
+			}
+			rolledBack = &status.Migration
+
+			return nil
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// DownTo rolls back the applied migrations newer than version, newest first, returning the migrations rolled back.
+// A version of 0 rolls back every migration.
+func (m *Migrator) DownTo(ctx context.Context, version int64) ([]Migration, error) {
+	var rolledBack []Migration
+
+	err := m.withLock(ctx, func(conn *pgx.Conn, statuses []MigrationStatus) error {
+		for _, status := range slices.Backward(statuses) {
+			if !status.Applied() || status.Version <= version {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, status.Migration, false); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, status.Migration)
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// To migrates the database to version, applying or rolling back migrations as needed.
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(migration Migration) bool { return migration.Version == version }) {
+		return nil, fmt.Errorf("no migration with version %d", version)
+	}
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if version < current {
+		return m.DownTo(ctx, version)
+	}
+
+	return m.UpTo(ctx, version)
+}
+
+// Version returns the version of the most recently applied migration, 0 when no migration is applied.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for _, status := range statuses {
+		if status.Applied() {
+			version = max(version, status.Version)
+		}
+	}
+
+	return version, nil
+}
+
+// Status returns every known migration ordered by version, with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+
+	err := m.db.Run(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		statuses, err = m.readStatuses(ctx, conn.Conn())
+		return err
+	})
+
+	return statuses, err
+}
+
+// withLock runs f on a single connection holding the migration advisory lock, which is released when f returns.
+func (m *Migrator) withLock(ctx context.Context, f func(*pgx.Conn, []MigrationStatus) error) error {
+	return m.db.Run(ctx, func(pooled *pgxpool.Conn) (err error) {
+		conn := pooled.Conn()
+		lockID := m.lockID()
+
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+
+		defer func() {
+			// The lock must be released on a live context, since the connection goes back to the pool holding it otherwise.
+			if _, unlockErr := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID); unlockErr != nil {
+				err = errors.Join(err, fmt.Errorf("releasing migration lock: %w", unlockErr))
+			}
+		}()
+
+		if err := m.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+
+		statuses, err := m.readStatuses(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return f(conn, statuses)
+	})
+}
+
+// lockID derives the advisory lock key from the table name, so migrators of different tables do not block each other.
+func (m *Migrator) lockID() int64 {
+	return postgres.LockKey(m.tableName)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *pgx.Conn) error {
+	exists, err := m.tableExists(ctx, conn)
+	if err != nil || exists || m.dryRun {
+		return err
+	}
+
+	table := pgx.Identifier{m.tableName}.Sanitize()
+
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE %s (
+			id serial NOT NULL PRIMARY KEY,
+			version_id bigint NOT NULL,
+			is_applied boolean NOT NULL,
+			tstamp timestamp NULL DEFAULT now()
+		)`, table)); err != nil {
+			return fmt.Errorf("creating migrations table: %w", err)
+		}
+
+		// goose records version 0 when creating the table
+		_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (0, true)", table))
+		return err
+	})
+}
+
+func (m *Migrator) tableExists(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", pgx.Identifier{m.tableName}.Sanitize()).Scan(&exists)
+	return exists, err
+}
+
+func (m *Migrator) readStatuses(ctx context.Context, conn *pgx.Conn) ([]MigrationStatus, error) {
+	exists, err := m.tableExists(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := map[int64]time.Time{}
+
+	if exists {
+		rows, err := conn.Query(ctx, fmt.Sprintf(
+			"SELECT version_id, max(tstamp) FROM %s WHERE is_applied AND version_id > 0 GROUP BY version_id",
+			pgx.Identifier{m.tableName}.Sanitize()))
+		if err != nil {
+			return nil, fmt.Errorf("reading applied migrations: %w", err)
+		}
+
+		var (
+			version int64
+			tstamp  *time.Time
+		)
+		_, err = pgx.ForEachRow(rows, []any{&version, &tstamp}, func() error {
+			appliedAt[version] = time.Unix(0, 0).UTC()
+			if tstamp != nil {
+				appliedAt[version] = *tstamp
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading applied migrations: %w", err)
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{Migration: migration, AppliedAt: appliedAt[migration.Version]})
+		delete(appliedAt, migration.Version)
+	}
+
+	for version := range appliedAt {
+		m.logger.With(labelVersion, version).WarnContext(ctx, "Applied migration has no migration file")
+	}
+
+	return statuses, nil
+}
+
+// checkMissing returns an error when a pending migration is older than an applied one, which usually means
+// migrations from different branches were merged, and applying it out of order needs a deliberate decision.
+func checkMissing(statuses []MigrationStatus) error {
+	var newestApplied int64
+	for _, status := range statuses {
+		if status.Applied() {
+			newestApplied = status.Version
+		}
+	}
+
+	for _, status := range statuses {
+		if !status.Applied() && status.Version < newestApplied {
+			return fmt.Errorf("migration '%s' is pending but older than the applied version %d", status.Source, newestApplied)
+		}
+	}
+
+	return nil
+}
+
+// apply runs the up or down statements of the migration and records it in the versions table, in a single
+// transaction unless the migration opted out of one.
+func (m *Migrator) apply(ctx context.Context, conn *pgx.Conn, migration Migration, up bool) error {
+	statements, direction := migration.UpStatements, "up"
+	record := fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, true)", pgx.Identifier{m.tableName}.Sanitize())
+	if !up {
+		statements, direction = migration.DownStatements, "down"
+		record = fmt.Sprintf("DELETE FROM %s WHERE version_id = $1", pgx.Identifier{m.tableName}.Sanitize())
+	}
+
+	logger := m.logger.With(labelVersion, migration.Version).With(labelMigration, migration.Source).With(labelDryRun, m.dryRun)
+
+	if m.dryRun {
+		for _, statement := range statements {
+			logger.InfoContextf(ctx, "Would run migration %s statement: %s", direction, statement)
+		}
+		return nil
+	}
+
+	begin := time.Now()
+	run := func(ctx context.Context, exec func(context.Context, string, ...any) error) error {
+		for i, statement := range statements {
+			if err := exec(ctx, statement); err != nil {
+				return fmt.Errorf("running statement %d of migration '%s' %s: %w", i+1, migration.Source, direction, err)
+			}
+		}
+
+		if err := exec(ctx, record, migration.Version); err != nil {
+			return fmt.Errorf("recording migration '%s' %s: %w", migration.Source, direction, err)
+		}
+
+		return nil
+	}
+
+	var err error
+	if migration.NoTransaction {
+		err = run(ctx, execFunc(conn.Exec))
+	} else {
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return run(ctx, execFunc(tx.Exec))
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.WithDur(time.Since(begin)).InfoContextf(ctx, "Ran migration %s", direction)
+
+	return nil
+}
+
+func execFunc(exec func(context.Context, string, ...any) (pgconn.CommandTag, error)) func(context.Context, string, ...any) error {
+	return func(ctx context.Context, sql string, args ...any) error {
+		_, err := exec(ctx, sql, args...)
+		return err
+	}
+}
@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
+	"time"
+)
+
+const (
+	// NotifyChannel is the channel notified when a message is enqueued, so the relay picks it up without waiting
+	// for its next poll.
+	NotifyChannel = "outbox"
+
+	enqueueQuery = `WITH message AS (
+		INSERT INTO outbox (topic, payload) VALUES ($1, $2) RETURNING id
+	)
+	SELECT pg_notify($3, message.id::text) FROM message`
+)
+
+// A Message is an event waiting in the outbox to be published.
+type Message struct {
+	ID        int64           `db:"id" json:"id"`
+	Topic     string          `db:"topic" json:"topic"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	Attempts  int             `db:"attempts" json:"attempts"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// Enqueue writes the payload, encoded as JSON, to the outbox in the context transaction. The message is only
+// published once the transaction commits, and is discarded with it if it rolls back.
+func Enqueue(ctx context.Context, topic string, payload any) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode outbox payload for topic '%s': %w", topic, err)
+	}
+
+	if _, err := pgx.ExecInsertContext(ctx, enqueueQuery, topic, encoded, NotifyChannel); err != nil {
+		return fmt.Errorf("could not enqueue outbox message for topic '%s': %w", topic, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderTopic     = "X-Outbox-Topic"
+	HeaderMessageID = "X-Outbox-Message-Id"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// A Publisher delivers outbox messages. A message is retried when Publish returns an error, so publishers may see
+// the same message more than once and consumers should deduplicate by the message ID.
+type Publisher interface {
+	Publish(context.Context, Message) error
+}
+
+// A PublisherFunc is a function that satisfies the [Publisher] interface.
+type PublisherFunc func(context.Context, Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher creates a [Publisher] that POSTs the payload of each message to the url, with the topic and
+// message ID in headers. Any status other than 2XX fails the delivery. A client timing out requests after 10 seconds
+// is used when client is nil.
+func NewWebhookPublisher(url string, client *http.Client) Publisher {
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	return webhookPublisher{url: url, client: client}
+}
+
+func (p webhookPublisher) Publish(ctx context.Context, m Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(m.Payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTopic, m.Topic)
+	req.Header.Set(HeaderMessageID, strconv.FormatInt(m.ID, 10))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type writerPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterPublisher creates a [Publisher] that writes each message to w as a line of JSON.
+func NewWriterPublisher(w io.Writer) Publisher {
+	return &writerPublisher{w: w}
+}
+
+// NewStdoutPublisher creates a [Publisher] that writes each message to stdout as a line of JSON.
+func NewStdoutPublisher() Publisher {
+	return NewWriterPublisher(os.Stdout)
+}
+
+func (p *writerPublisher) Publish(_ context.Context, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return json.NewEncoder(p.w).Encode(m)
+}
+
+// A MemoryPublisher keeps published messages in memory, for consumers in the same process and for tests.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+// Messages returns the messages published so far, oldest first.
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Message(nil), p.messages...)
+}
@@ -0,0 +1,306 @@
+package outbox
+
+import (
+	"context"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acsync "github.com/zhughes3/go-accelerate/pkg/sync"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchSize      = 100
+	defaultPollInterval   = 5 * time.Second
+	defaultLeaseDuration  = 5 * time.Minute
+	defaultMaxAttempts    = 10
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	// retryJitter spreads the retries of messages that failed together, see [retry.Policy.Jitter]
+	retryJitter = 0.5
+
+	labelMessageID = "messageId"
+	labelTopic     = "topic"
+	labelAttempts  = "attempts"
+
+	// claimPending leases a batch of pending messages by pushing back when they are available, skipping those
+	// claimed by the relays of other replicas. The new available_at is the lease, which later updates check, so a
+	// relay whose lease expired cannot overwrite the outcome recorded by the relay that claimed the message next.
+	claimPending = `UPDATE outbox SET available_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE delivered_at IS NULL AND dead_lettered_at IS NULL AND available_at <= NOW()
+			ORDER BY available_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, payload, attempts, created_at, available_at`
+	markDelivered = `UPDATE outbox SET delivered_at = NOW(), attempts = attempts + 1, last_error = NULL
+		WHERE id = $1 AND available_at = $2`
+	markRetry = `UPDATE outbox SET attempts = attempts + 1, last_error = $3,
+		available_at = NOW() + $4 * INTERVAL '1 millisecond' WHERE id = $1 AND available_at = $2`
+	markDeadLettered = `UPDATE outbox SET attempts = attempts + 1, last_error = $3, dead_lettered_at = NOW()
+		WHERE id = $1 AND available_at = $2`
+)
+
+// A claimedMessage is a message leased by a relay until LeasedUntil.
+type claimedMessage struct {
+	Message
+	LeasedUntil time.Time `db:"available_at"`
+}
+
+// A Relay publishes the messages in the outbox. It polls the outbox periodically, and right away when a message is
+// enqueued. Relays on several replicas can run at the same time, since each leases the messages it is publishing.
+// Messages are published outside of any transaction, so no connection or row lock is held while publishing, and a
+// message whose lease expires before its outcome is recorded, e.g. because its relay crashed, is published again.
+//
+// A message that fails to publish is retried with exponential backoff, and dead-lettered, i.e. left in the outbox
+// and no longer retried, once it runs out of attempts.
+type Relay struct {
+	logger     slog.Logger
+	db         postgres.DB
+	publisher  Publisher
+	publishers map[string]Publisher
+
+	batchSize     int
+	pollInterval  time.Duration
+	leaseDuration time.Duration
+	retry         retry.Policy
+
+	state *acsync.StateMachine
+	bgWG  sync.WaitGroup
+	stop  chan any
+	// cancel cancels the context of the messages being published, so shutdown does not wait for slow publishers
+	cancel context.CancelFunc
+}
+
+// A RelayOption configures a [Relay].
+type RelayOption func(*Relay)
+
+// WithTopicPublisher publishes the messages of the topic with p, instead of the default publisher of the [Relay].
+func WithTopicPublisher(topic string, p Publisher) RelayOption {
+	return func(r *Relay) {
+		r.publishers[topic] = p
+	}
+}
+
+// WithBatchSize sets the maximum number of messages claimed and published at a time. Defaults to 100.
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = n
+	}
+}
+
+// WithPollInterval sets how often the outbox is polled for messages. Defaults to 5 seconds.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = d
+	}
+}
+
+// WithLeaseDuration sets how long the messages of a batch are leased to the relay publishing them. Messages not
+// published before their lease expires are left to the next batch. Defaults to 5 minutes.
+func WithLeaseDuration(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.leaseDuration = d
+	}
+}
+
+// WithRetryPolicy sets how a message is retried after failing to publish. Defaults to 10 attempts, with a backoff
+// from 1 second up to 5 minutes.
+func WithRetryPolicy(policy retry.Policy) RelayOption {
+	return func(r *Relay) {
+		r.retry = policy
+	}
+}
+
+// NewRelay creates a [Relay] publishing the messages in the outbox with the publisher.
+func NewRelay(logger slog.Logger, db postgres.DB, publisher Publisher, opts ...RelayOption) *Relay {
+	r := Relay{
+		logger:        logger,
+		db:            db,
+		publisher:     publisher,
+		publishers:    map[string]Publisher{},
+		batchSize:     defaultBatchSize,
+		pollInterval:  defaultPollInterval,
+		leaseDuration: defaultLeaseDuration,
+		retry: retry.Policy{
+			MaxAttempts:    defaultMaxAttempts,
+			InitialBackoff: defaultInitialBackoff,
+			MaxBackoff:     defaultMaxBackoff,
+			Jitter:         retryJitter,
+		},
+		stop: make(chan any),
+		state: acsync.NewStateMachineBuilder(logger).
+			WithComponentName("outbox_relay").
+			WithIgnoreAlreadyAtEndError(true).
+			Build(),
+	}
+
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	return &r
+}
+
+// Start publishes the messages in the outbox in a background goroutine, until the relay is shut down.
+func (r *Relay) Start(ctx context.Context) error {
+	return r.state.Start(ctx, func() error {
+		// The subscription and the publishing end when the relay is shut down, rather than with the start context.
+		subCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		r.cancel = cancel
+
+		notifications, err := r.db.Subscribe(subCtx, NotifyChannel)
+		if err != nil {
+			r.logger.WithError(err).WarnContext(ctx, "Problem subscribing to outbox notifications; polling only")
+		}
+
+		r.bgWG.Add(1)
+		go func() {
+			defer r.bgWG.Done()
+			defer cancel()
+
+			ticker := time.NewTicker(r.pollInterval)
+			defer ticker.Stop()
+
+			for {
+				r.relayPending(subCtx)
+
+				select {
+				case <-r.stop:
+					return
+				case <-ticker.C:
+				case _, ok := <-notifications:
+					if !ok {
+						// the database is shutting down; keep polling until the relay is shut down as well
+						notifications = nil
+					}
+				}
+			}
+		}()
+
+		return nil
+	})
+}
+
+// Shutdown stops publishing, canceling the messages being published, which are published again once their lease
+// expires.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	return r.state.Shutdown(ctx, func() error {
+		r.logger.InfoContext(ctx, "Shutting down the outbox relay...")
+		close(r.stop)
+		if r.cancel != nil {
+			r.cancel()
+		}
+		r.bgWG.Wait()
+		r.logger.InfoContext(ctx, "Shutting down the outbox relay...complete")
+
+		return nil
+	})
+}
+
+// relayPending publishes batches of messages until the outbox has no more messages that are due.
+func (r *Relay) relayPending(ctx context.Context) {
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		n, err := r.relayBatch(ctx)
+		if err != nil {
+			r.logger.WithError(err).WarnContext(ctx, "Problem relaying outbox messages")
+			return
+		}
+
+		if n < r.batchSize {
+			return
+		}
+	}
+}
+
+// relayBatch claims a batch of messages and publishes them one at a time, returning how many were claimed.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	var messages []claimedMessage
+
+	err := r.db.TransactionContext(ctx, func(ctx context.Context) error {
+		var err error
+		messages, err = pgx.ScanAllContext[claimedMessage](ctx, claimPending, r.batchSize, r.leaseDuration.Milliseconds())
+		return err
+	}, postgres.WithoutRetries())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range messages {
+		if ctx.Err() != nil || time.Now().After(m.LeasedUntil) {
+			// The remaining messages are published again once their lease expires.
+			break
+		}
+
+		if err := r.relay(ctx, m); err != nil {
+			return len(messages), err
+		}
+	}
+
+	return len(messages), nil
+}
+
+func (r *Relay) relay(ctx context.Context, m claimedMessage) error {
+	publishErr := r.publisherFor(m.Topic).Publish(ctx, m.Message)
+	if publishErr == nil {
+		return r.recordOutcome(ctx, m, markDelivered, m.ID, m.LeasedUntil)
+	}
+
+	if ctx.Err() != nil {
+		// Publishing was canceled by shutdown, which is not the fault of the message.
+		return nil
+	}
+
+	attempts := m.Attempts + 1
+	logger := r.logger.WithError(publishErr).With(labelMessageID, m.ID).With(labelTopic, m.Topic).With(labelAttempts, attempts)
+
+	if attempts >= r.retry.MaxAttempts {
+		logger.ErrorContext(ctx, "Dead-lettering outbox message that failed to publish")
+		return r.recordOutcome(ctx, m, markDeadLettered, m.ID, m.LeasedUntil, publishErr.Error())
+	}
+
+	backoff := r.retry.Backoff(attempts)
+	logger.WithDur(backoff).WarnContext(ctx, "Problem publishing outbox message; retrying")
+
+	return r.recordOutcome(ctx, m, markRetry, m.ID, m.LeasedUntil, publishErr.Error(), backoff.Milliseconds())
+}
+
+// recordOutcome runs the update recording the outcome of publishing the message, as long as the relay still holds
+// the lease of the message.
+func (r *Relay) recordOutcome(ctx context.Context, m claimedMessage, query string, args ...any) error {
+	var updated bool
+
+	err := r.db.TransactionContext(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = pgx.ExecUpdateContext(ctx, query, args...)
+		return err
+	}, postgres.WithoutRetries())
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		r.logger.With(labelMessageID, m.ID).With(labelTopic, m.Topic).
+			WarnContext(ctx, "Lease of outbox message expired before its outcome was recorded; it may be published twice")
+	}
+
+	return nil
+}
+
+func (r *Relay) publisherFor(topic string) Publisher {
+	if p, ok := r.publishers[topic]; ok {
+		return p
+	}
+
+	return r.publisher
+}
@@ -0,0 +1,65 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"strings"
+)
+
+// A cursor is the position of a page in a list. It is opaque to clients, and signed so they cannot forge one that
+// reads rows outside of the order they asked for.
+type cursor struct {
+	OrderBy string   `json:"o"`
+	After   []string `json:"a"`
+}
+
+// encodeCursor encodes the cursor as URL safe base64 JSON, followed by a dot and its HMAC-SHA256 signature.
+func encodeCursor(key []byte, c cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("could not encode cursor: %w", err)
+	}
+
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(sign(key, payload)), nil
+}
+
+func decodeCursor(key []byte, token string) (cursor, error) {
+	invalid := acerrors.NewInvalidInputError("cursor is invalid")
+
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return cursor{}, invalid
+	}
+
+	encoding := base64.RawURLEncoding
+	payload, err := encoding.DecodeString(encodedPayload)
+	if err != nil {
+		return cursor{}, invalid
+	}
+	signature, err := encoding.DecodeString(encodedSignature)
+	if err != nil {
+		return cursor{}, invalid
+	}
+
+	if !hmac.Equal(signature, sign(key, payload)) {
+		return cursor{}, invalid
+	}
+
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil || len(c.After) != 2 {
+		return cursor{}, invalid
+	}
+
+	return c, nil
+}
+
+func sign(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
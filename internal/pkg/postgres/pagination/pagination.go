@@ -0,0 +1,248 @@
+package pagination
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/pkg/api"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit    = 20
+	defaultMaxLimit = 100
+
+	// descendingPrefix marks a descending order, e.g. "-created_at".
+	descendingPrefix = "-"
+)
+
+// Config configures the pages of a [Paginator].
+type Config struct {
+	// CursorKey signs the cursors, so clients cannot forge them. It is required when running several replicas, which
+	// must all share it for cursors to work across them. A random key is generated when not set, with a warning,
+	// which is only fit for a single replica, and invalidates cursors on restart.
+	CursorKey string `env:"CURSOR_KEY"`
+	// DefaultLimit is the number of records of a page when the request has no limit. Defaults to 20 when not set.
+	DefaultLimit int `env:"DEFAULT_LIMIT"`
+	// MaxLimit caps the limit requested. Defaults to 100 when not set.
+	MaxLimit int `env:"MAX_LIMIT"`
+}
+
+// A Request asks for a page of a list. It is bound from the query string by [achttp.BindRequestParams], and is
+// meant to be embedded in the request of a list endpoint.
+type Request struct {
+	// Limit is the maximum number of records of the page.
+	Limit int `query:"limit"`
+	// Cursor is the next_cursor of the previous page, and is blank for the first page.
+	Cursor string `query:"cursor"`
+	// OrderBy is the name of the column the list is sorted by, prefixed with "-" for a descending order.
+	OrderBy string `query:"order_by"`
+}
+
+// A Column of T that a list can be sorted by.
+type Column[T any] struct {
+	// Name is the name of the column in the order_by parameter.
+	Name string
+	// SQL is the name of the column in the results of the query being paginated. Rows are compared by their
+	// values, so the column must not be nullable.
+	SQL string
+	// Value returns the value of the column for the record, which the next page starts after.
+	Value func(T) any
+}
+
+// A Spec is the allowlist of the columns a list of T can be sorted by.
+type Spec[T any] struct {
+	// Columns are the columns clients can sort by.
+	Columns []Column[T]
+	// TieBreaker is a unique column, usually the primary key, that orders the records with equal sort values.
+	TieBreaker Column[T]
+	// DefaultOrderBy is the order of requests without one, e.g. "-created_at".
+	DefaultOrderBy string
+}
+
+// A Paginator splits lists of T into pages with keyset pagination: each page continues after the sort values of
+// the last record of the previous page, rather than skipping an offset. Pages are therefore stable when records
+// are inserted, and equally cheap however deep they are, given an index on the sort and tie breaker columns.
+type Paginator[T any] struct {
+	spec     Spec[T]
+	key      []byte
+	limit    int
+	maxLimit int
+}
+
+// NewPaginator creates a [Paginator] of the lists described by the spec, filling in the defaults for unset config.
+func NewPaginator[T any](logger slog.Logger, config Config, spec Spec[T]) *Paginator[T] {
+	p := Paginator[T]{
+		spec:     spec,
+		key:      []byte(config.CursorKey),
+		limit:    config.DefaultLimit,
+		maxLimit: config.MaxLimit,
+	}
+
+	if acstrings.IsBlank(config.CursorKey) {
+		logger.WarnContext(context.Background(), "No pagination cursor key is configured; signing cursors with a random key, "+
+			"so they are rejected by other replicas and after a restart")
+		p.key = make([]byte, 32)
+		_, _ = rand.Read(p.key)
+	}
+	if p.maxLimit <= 0 {
+		p.maxLimit = defaultMaxLimit
+	}
+	if p.limit <= 0 {
+		p.limit = min(defaultLimit, p.maxLimit)
+	}
+
+	return &p
+}
+
+// A Page of a list, built by [Paginator.Page].
+type Page struct {
+	limit      int
+	orderBy    string
+	columns    [2]string
+	descending bool
+	// after holds the sort and tie breaker values the page starts after, and is empty for the first page
+	after []string
+}
+
+// Page validates the request against the allowlist of the spec, returning an [acerrors.InvalidInputError] when it
+// asks for an unknown order, or has a cursor that is malformed, tampered with, or from a list with another order.
+// Limits above the maximum are capped.
+func (p *Paginator[T]) Page(req Request) (Page, error) {
+	if req.Limit < 0 {
+		return Page{}, acerrors.NewInvalidInputErrorf("limit must be positive; limit: %d", req.Limit)
+	}
+
+	limit := p.limit
+	if req.Limit > 0 {
+		limit = min(req.Limit, p.maxLimit)
+	}
+
+	var c cursor
+	if acstrings.IsNotBlank(req.Cursor) {
+		var err error
+		if c, err = decodeCursor(p.key, req.Cursor); err != nil {
+			return Page{}, err
+		}
+
+		if acstrings.IsBlank(req.OrderBy) {
+			req.OrderBy = c.OrderBy
+		} else if req.OrderBy != c.OrderBy {
+			return Page{}, acerrors.NewInvalidInputError("cursor is from a list with another order_by")
+		}
+	}
+
+	if acstrings.IsBlank(req.OrderBy) {
+		req.OrderBy = p.spec.DefaultOrderBy
+	}
+
+	column, descending, err := p.orderColumn(req.OrderBy)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return Page{
+		limit:      limit,
+		orderBy:    req.OrderBy,
+		columns:    [2]string{column.SQL, p.spec.TieBreaker.SQL},
+		descending: descending,
+		after:      c.After,
+	}, nil
+}
+
+func (p *Paginator[T]) orderColumn(orderBy string) (Column[T], bool, error) {
+	name, descending := strings.CutPrefix(orderBy, descendingPrefix)
+
+	for _, column := range p.spec.Columns {
+		if column.Name == name {
+			return column, descending, nil
+		}
+	}
+
+	names := make([]string, 0, len(p.spec.Columns))
+	for _, column := range p.spec.Columns {
+		names = append(names, column.Name)
+	}
+
+	return Column[T]{}, false, acerrors.NewInvalidInputErrorf("order_by must be one of %s, optionally prefixed with '-'; order_by: %s",
+		strings.Join(names, ", "), orderBy)
+}
+
+// Result trims the records of the page, queried with [Page.Query], to its limit, and describes the page. The
+// metadata has a next cursor when there are more records.
+func (p *Paginator[T]) Result(page Page, records []T) ([]T, api.ListMeta, error) {
+	more := len(records) > page.limit
+	if more {
+		records = records[:page.limit]
+	}
+
+	meta := api.ListMeta{Count: int32(len(records)), More: more}
+	if !more {
+		return records, meta, nil
+	}
+
+	column, _, err := p.orderColumn(page.orderBy)
+	if err != nil {
+		return nil, api.ListMeta{}, err
+	}
+
+	last := records[len(records)-1]
+	meta.NextCursor, err = encodeCursor(p.key, cursor{
+		OrderBy: page.orderBy,
+		After:   []string{cursorValue(column.Value(last)), cursorValue(p.spec.TieBreaker.Value(last))},
+	})
+	if err != nil {
+		return nil, api.ListMeta{}, err
+	}
+
+	return records, meta, nil
+}
+
+// Limit returns the maximum number of records of the page.
+func (p Page) Limit() int {
+	return p.limit
+}
+
+// Query restricts the results of the query to the page, with args being the arguments of the query. It returns the
+// SQL and the arguments of the page query, which fetches one record more than the limit so [Paginator.Result] can
+// tell whether there are more. The name of a query loaded by a [postgres.QueryRegistry] is kept.
+func (p Page) Query(query string, args ...any) (string, []any) {
+	var b strings.Builder
+
+	if _, ok := postgres.QueryName(query); ok {
+		var nameComment string
+		nameComment, query, _ = strings.Cut(query, "\n")
+		b.WriteString(nameComment + "\n")
+	}
+
+	fmt.Fprintf(&b, "SELECT * FROM (\n%s\n) AS page", strings.TrimSuffix(strings.TrimSpace(query), ";"))
+
+	comparison, direction := ">", "ASC"
+	if p.descending {
+		comparison, direction = "<", "DESC"
+	}
+
+	if len(p.after) > 0 {
+		// The row comparison matches the order, and uses an index on both columns.
+		fmt.Fprintf(&b, " WHERE (%s, %s) %s ($%d, $%d)", p.columns[0], p.columns[1], comparison, len(args)+1, len(args)+2)
+		args = append(args, p.after[0], p.after[1])
+	}
+
+	fmt.Fprintf(&b, " ORDER BY %s %s, %s %s LIMIT %d", p.columns[0], direction, p.columns[1], direction, p.limit+1)
+
+	return b.String(), args
+}
+
+// cursorValue formats a value as text, which the database parses into the type of the column it is compared to.
+func cursorValue(v any) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(v)
+}
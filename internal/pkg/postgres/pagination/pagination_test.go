@@ -0,0 +1,195 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type record struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+var spec = Spec[record]{
+	Columns: []Column[record]{
+		{Name: "name", SQL: "title", Value: func(r record) any { return r.Name }},
+		{Name: "created_at", SQL: "created_at", Value: func(r record) any { return r.CreatedAt }},
+	},
+	TieBreaker:     Column[record]{Name: "id", SQL: "id", Value: func(r record) any { return r.ID }},
+	DefaultOrderBy: "-created_at",
+}
+
+func newPaginator(key string) *Paginator[record] {
+	return NewPaginator(slog.Base(), Config{CursorKey: key, DefaultLimit: 2, MaxLimit: 10}, spec)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := []byte("key")
+	c := cursor{OrderBy: "-created_at", After: []string{"2024-12-01T00:00:00Z", "tl_1"}}
+
+	token, err := encodeCursor(key, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := decodeCursor(key, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if decoded.OrderBy != c.OrderBy || !slices.Equal(decoded.After, c.After) {
+		t.Errorf("decoded %+v, want %+v", decoded, c)
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	key := []byte("key")
+	token, err := encodeCursor(key, cursor{OrderBy: "name", After: []string{"a", "tl_1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged, err := encodeCursor([]byte("another key"), cursor{OrderBy: "name", After: []string{"a", "tl_1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"o":"name","a":["z","tl_9"]}`))
+
+	oneValue, err := encodeCursor(key, cursor{OrderBy: "name", After: []string{"a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"no signature":       payload,
+		"tampered payload":   tampered + "." + signature,
+		"tampered signature": payload + "." + base64.RawURLEncoding.EncodeToString([]byte("signature")),
+		"other key":          forged,
+		"not base64":         "!!!." + signature,
+		"one value":          oneValue,
+		"empty":              ".",
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeCursor(key, token)
+
+			var invalid acerrors.InvalidInputError
+			if !errors.As(err, &invalid) {
+				t.Errorf("expected an invalid input error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPaginatorPages(t *testing.T) {
+	p := newPaginator("key")
+	now := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	records := []record{
+		{ID: "tl_3", Name: "c", CreatedAt: now},
+		{ID: "tl_2", Name: "b", CreatedAt: now.Add(-time.Hour)},
+		{ID: "tl_1", Name: "a", CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	first, err := p.Page(Request{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query, args := first.Query("-- name: timelines.ListPage\nSELECT * FROM timelines WHERE user_id = $1;", "u_1")
+	wantQuery := "-- name: timelines.ListPage\nSELECT * FROM (\nSELECT * FROM timelines WHERE user_id = $1\n) AS page" +
+		" ORDER BY created_at DESC, id DESC LIMIT 3"
+	if query != wantQuery || len(args) != 1 {
+		t.Errorf("first page query = %q %v, want %q", query, args, wantQuery)
+	}
+
+	page, meta, err := p.Result(first, records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page) != 2 || !meta.More || meta.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v %+v", page, meta)
+	}
+
+	next, err := p.Page(Request{Cursor: meta.NextCursor})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query, args = next.Query("SELECT * FROM timelines WHERE user_id = $1", "u_1")
+	wantQuery = "SELECT * FROM (\nSELECT * FROM timelines WHERE user_id = $1\n) AS page" +
+		" WHERE (created_at, id) < ($2, $3) ORDER BY created_at DESC, id DESC LIMIT 3"
+	wantArgs := []any{"u_1", "2024-11-30T23:00:00Z", "tl_2"}
+	if query != wantQuery || !slices.Equal(args, wantArgs) {
+		t.Errorf("next page query = %q %v, want %q %v", query, args, wantQuery, wantArgs)
+	}
+
+	last, meta, err := p.Result(next, records[2:])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(last) != 1 || meta.More || meta.NextCursor != "" {
+		t.Errorf("unexpected last page: %+v %+v", last, meta)
+	}
+}
+
+func TestPaginatorRejectsInvalidRequests(t *testing.T) {
+	p := newPaginator("key")
+
+	first, err := p.Page(Request{OrderBy: "name"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := []record{{ID: "tl_1", Name: "a"}, {ID: "tl_2", Name: "b"}, {ID: "tl_3", Name: "c"}}
+
+	_, meta, err := p.Result(first, records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, otherMeta, err := newPaginator("another key").Result(first, records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]Request{
+		"order_by mismatch":     {Cursor: meta.NextCursor, OrderBy: "-created_at"},
+		"unknown order_by":      {OrderBy: "user_id"},
+		"negative limit":        {Limit: -1},
+		"cursor of another key": {Cursor: otherMeta.NextCursor},
+	}
+
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.Page(req)
+
+			var invalid acerrors.InvalidInputError
+			if !errors.As(err, &invalid) {
+				t.Errorf("expected an invalid input error, got %v", err)
+			}
+		})
+	}
+
+	if page, err := p.Page(Request{Cursor: meta.NextCursor, OrderBy: "name"}); err != nil || page.orderBy != "name" {
+		t.Errorf("a cursor with its own order_by should be accepted: %+v, %v", page, err)
+	}
+}
+
+func TestPaginatorCapsLimit(t *testing.T) {
+	page, err := newPaginator("key").Page(Request{Limit: 1000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if page.Limit() != 10 {
+		t.Errorf("limit = %d, want 10", page.Limit())
+	}
+}
@@ -0,0 +1,112 @@
+package pgx
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+)
+
+func newBatch(statements []postgres.BatchStatement) *pgx.Batch {
+	batch := &pgx.Batch{}
+	for _, s := range statements {
+		batch.Queue(s.Query, s.Arguments...)
+	}
+
+	return batch
+}
+
+// ExecBatchContext sends all the statements to the database in a single round trip, on the context transaction.
+// A result is returned for every statement. The returned error is the first statement error, if any, since
+// postgres aborts the transaction after a failed statement and the remaining statements will fail as well.
+func ExecBatchContext(ctx context.Context, statements []postgres.BatchStatement) ([]postgres.BatchResult, error) {
+	results := make([]postgres.BatchResult, len(statements))
+
+	errs, err := sendBatch(ctx, statements, func(i int, rows pgx.Rows) error {
+		records, err := pgx.CollectRows(rows, pgx.RowToMap)
+		if err != nil {
+			return err
+		}
+
+		results[i].Rows = records
+		results[i].RowsAffected = rows.CommandTag().RowsAffected()
+		return nil
+	})
+
+	for i := range results {
+		results[i].Err = errs[i]
+	}
+
+	return results, err
+}
+
+// QueryBatchContext sends all the statements to the database in a single round trip, on the context transaction,
+// and maps the rows returned by each statement with mapRow. The mapped rows are returned in statement order.
+func QueryBatchContext[T any](ctx context.Context, mapRow RowMapper[T], statements []postgres.BatchStatement) ([][]T, error) {
+	entities := make([][]T, len(statements))
+
+	_, err := sendBatch(ctx, statements, func(i int, rows pgx.Rows) error {
+		for rows.Next() {
+			entity, err := mapRow(rows)
+			if err != nil {
+				return err
+			}
+
+			entities[i] = append(entities[i], entity)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// ScanBatchContext is like [QueryBatchContext], mapping each row into a T by matching column names
+// to the `db` struct tags of T.
+func ScanBatchContext[T any](ctx context.Context, statements []postgres.BatchStatement) ([][]T, error) {
+	return QueryBatchContext(ctx, func(rows pgx.Rows) (T, error) {
+		return pgx.RowToStructByNameLax[T](rows)
+	}, statements)
+}
+
+// sendBatch sends the statements on the context transaction and calls read with the rows of each statement,
+// in order. It returns the error of each statement, along with the first error encountered.
+func sendBatch(ctx context.Context, statements []postgres.BatchStatement, read func(int, pgx.Rows) error) ([]error, error) {
+	errs := make([]error, len(statements))
+	if len(statements) == 0 {
+		return errs, nil
+	}
+
+	br := mustGetContextTx(ctx).SendBatch(ctx, newBatch(statements))
+
+	var firstErr error
+	for i := range statements {
+		errs[i] = postgres.TranslateError(readBatchResult(br, i, read))
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+
+	if err := br.Close(); err != nil && firstErr == nil {
+		firstErr = postgres.TranslateError(err)
+	}
+
+	return errs, firstErr
+}
+
+func readBatchResult(br pgx.BatchResults, i int, read func(int, pgx.Rows) error) error {
+	rows, err := br.Query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := read(i, rows); err != nil {
+		return err
+	}
+
+	rows.Close()
+	return rows.Err()
+}
@@ -0,0 +1,34 @@
+package pgx
+
+import (
+	"context"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
+)
+
+// Operations labeling the attempts counted by [postgres.RegisterConnectionAttempts].
+const (
+	operationConnect = "connect"
+	operationAcquire = "acquire"
+)
+
+// reportingAttempts makes the policy log and count each failed attempt of the operation that is retried.
+func (db *db) reportingAttempts(operation string, policy retry.Policy) retry.Policy {
+	policy.OnRetry = func(ctx context.Context, attempt retry.Attempt) {
+		db.attempts.WithLabelValues(operation, postgres.AttemptOutcomeRetry).Inc()
+		db.logger.WithError(attempt.Err).WithDur(attempt.Backoff).With("operation", operation).
+			WarnContextf(ctx, "Problem reaching the database; retry: %d", attempt.Number)
+	}
+
+	return policy
+}
+
+// countAttempt counts the last attempt of the operation, which succeeded when err is nil.
+func (db *db) countAttempt(operation string, err error) {
+	outcome := postgres.AttemptOutcomeSuccess
+	if err != nil {
+		outcome = postgres.AttemptOutcomeFailure
+	}
+
+	db.attempts.WithLabelValues(operation, outcome).Inc()
+}
@@ -0,0 +1,168 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// A copyField is a struct field copied into a column, with the index path to reach it from the struct.
+type copyField struct {
+	column string
+	index  []int
+}
+
+// CopyFromContext bulk loads the rows into the table with COPY FROM, on the context transaction, returning the
+// number of rows copied. See [CopyFromSeqContext].
+func CopyFromContext[T any](ctx context.Context, table string, rows []T, columns ...string) (int64, error) {
+	return CopyFromSeqContext(ctx, table, slices.Values(rows), columns...)
+}
+
+// CopyFromSeqContext bulk loads the rows produced by the iterator into the table with COPY FROM, on the context
+// transaction, returning the number of rows copied. The rows are streamed, so the iterator is consumed while the
+// copy is in progress.
+//
+// T is a struct, or a pointer to one, whose fields are mapped to columns the same way [ScanAllContext] maps them:
+// by `db` tag, or the snake cased field name when untagged. Fields tagged `db:"-"` are skipped and embedded structs
+// are flattened. When columns are given, only those columns are copied, which leaves the rest to their defaults.
+// Values are encoded by the type of their column, so nil pointers are copied as NULL, times as timestamps, and
+// structs and maps copied into jsonb columns are encoded as JSON.
+func CopyFromSeqContext[T any](ctx context.Context, table string, rows iter.Seq[T], columns ...string) (int64, error) {
+	fields, err := copyFieldsOf(reflect.TypeFor[T](), columns)
+	if err != nil {
+		return 0, err
+	}
+
+	next, stop := iter.Pull(rows)
+	defer stop()
+
+	src := &seqCopySource[T]{next: next, fields: fields}
+
+	columnNames := make([]string, len(fields))
+	for i, f := range fields {
+		columnNames[i] = f.column
+	}
+
+	n, err := mustGetContextTx(ctx).CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columnNames, src)
+	if err != nil {
+		return n, postgres.TranslateError(err)
+	}
+
+	return n, nil
+}
+
+// seqCopySource is a [pgx.CopyFromSource] of the structs produced by an iterator.
+type seqCopySource[T any] struct {
+	next   func() (T, bool)
+	fields []copyField
+
+	current T
+}
+
+func (s *seqCopySource[T]) Next() bool {
+	var ok bool
+	s.current, ok = s.next()
+	return ok
+}
+
+func (s *seqCopySource[T]) Values() ([]any, error) {
+	v := reflect.ValueOf(&s.current).Elem()
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, fmt.Errorf("cannot copy a nil %s", v.Type())
+		}
+		v = v.Elem()
+	}
+
+	values := make([]any, len(s.fields))
+	for i, f := range s.fields {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			// a field of a nil embedded struct pointer
+			values[i] = nil
+			continue
+		}
+		values[i] = fv.Interface()
+	}
+
+	return values, nil
+}
+
+func (s *seqCopySource[T]) Err() error {
+	return nil
+}
+
+// copyFieldsOf returns the fields of the struct type t to copy, limited to and ordered like columns when given.
+func copyFieldsOf(t reflect.Type, columns []string) ([]copyField, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot copy rows of %s, which is not a struct", t)
+	}
+
+	fields := appendCopyFields(nil, t, nil)
+	if len(columns) == 0 {
+		return fields, nil
+	}
+
+	selected := make([]copyField, 0, len(columns))
+	for _, column := range columns {
+		i := slices.IndexFunc(fields, func(f copyField) bool { return f.column == column })
+		if i < 0 {
+			return nil, fmt.Errorf("%s has no field for column '%s'", t, column)
+		}
+		selected = append(selected, fields[i])
+	}
+
+	return selected, nil
+}
+
+func appendCopyFields(fields []copyField, t reflect.Type, indexPrefix []int) []copyField {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup("db")
+		tag, _, _ = strings.Cut(tag, ",")
+		if tag == "-" {
+			continue
+		}
+
+		index := append(slices.Clone(indexPrefix), field.Index...)
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && !tagged && fieldType.Kind() == reflect.Struct {
+			fields = appendCopyFields(fields, fieldType, index)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		column := tag
+		if !tagged {
+			column = dbscan.SnakeCaseMapper(field.Name)
+		}
+
+		if !slices.ContainsFunc(fields, func(f copyField) bool { return f.column == column }) {
+			fields = append(fields, copyField{column: column, index: index})
+		}
+	}
+
+	return fields
+}
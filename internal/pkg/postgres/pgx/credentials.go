@@ -0,0 +1,108 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"time"
+)
+
+const defaultCredentialRefreshInterval = time.Minute
+
+// WithCredentialProvider sets the provider of the credentials of new connections, instead of the one of the
+// [postgres.Config]. See [postgres.Config.CredentialProvider].
+func WithCredentialProvider(provider postgres.CredentialProvider) Option {
+	return func(db *db) {
+		db.credentials = provider
+	}
+}
+
+// newPoolConfig creates the configuration of a pool whose connections get their credentials from the provider.
+func (db *db) newPoolConfig(cc *pgx.ConnConfig) *pgxpool.Config {
+	cpc := db.config.NewPoolConfig(cc)
+	cpc.BeforeConnect = db.applyCredentials
+
+	return cpc
+}
+
+// applyCredentials sets the credentials of the provider on the configuration of a new connection.
+func (db *db) applyCredentials(ctx context.Context, cc *pgx.ConnConfig) error {
+	credentials, err := db.credentials.Credentials(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get database credentials: %w", err)
+	}
+
+	if acstrings.IsNotBlank(credentials.User) {
+		cc.User = credentials.User
+	}
+	cc.Password = credentials.Password
+
+	return nil
+}
+
+// startCredentialRefresh checks the credentials of the provider for changes in a background goroutine, until the
+// database is shut down. When they change, the pools are reset: idle connections are closed right away and acquired
+// ones when they are released, so new connections are opened with the new credentials without failing queries in
+// flight. Static credentials are never checked. Only credentials actually fetched are compared, so when fetching them
+// fails at first, the first ones fetched afterwards are taken as the current ones without resetting the pools.
+func (db *db) startCredentialRefresh(ctx context.Context) {
+	if _, ok := db.credentials.(postgres.StaticCredentials); ok {
+		return
+	}
+
+	var current *postgres.Credentials
+	if credentials, err := db.credentials.Credentials(ctx); err != nil {
+		db.logger.WithError(err).WarnContext(ctx, "Problem getting database credentials")
+	} else {
+		current = &credentials
+	}
+
+	interval := defaultCredentialRefreshInterval
+	if db.config.CredentialRefreshInterval > 0 {
+		interval = db.config.CredentialRefreshInterval
+	}
+
+	db.bgWG.Add(1)
+	go func() {
+		defer db.bgWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-db.stop:
+				return
+			case <-ticker.C:
+				current = db.refreshCredentials(context.Background(), current)
+			}
+		}
+	}()
+}
+
+// refreshCredentials recycles the connections of the pools when the credentials of the provider are no longer the
+// current ones, returning the credentials now current. Nothing is recycled when no credentials were current yet.
+func (db *db) refreshCredentials(ctx context.Context, current *postgres.Credentials) *postgres.Credentials {
+	credentials, err := db.credentials.Credentials(ctx)
+	if err != nil {
+		db.logger.WithError(err).WarnContext(ctx, "Problem refreshing database credentials")
+		return current
+	}
+
+	if current == nil || credentials == *current {
+		return &credentials
+	}
+
+	db.logger.InfoContext(ctx, "Database credentials changed; recycling connections")
+	db.sqldb.Reset()
+	if db.replicas != nil {
+		for _, r := range db.replicas.replicas {
+			r.pool.Reset()
+		}
+	}
+
+	return &credentials
+}
@@ -0,0 +1,218 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acsync "github.com/zhughes3/go-accelerate/pkg/sync"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultLeaderElectionInterval = 5 * time.Second
+
+	labelElection = "election"
+)
+
+// A LeaderElector elects a single leader among the replicas of a service, by holding a session advisory lock on a
+// dedicated connection. The leader keeps its leadership until it shuts down or loses its connection, at which
+// point another replica takes over the next time it tries to take the lock.
+type LeaderElector struct {
+	logger   slog.Logger
+	db       postgres.DB
+	name     string
+	key      int64
+	interval time.Duration
+
+	leader atomic.Bool
+
+	// mu guards conn and watchers
+	mu       sync.Mutex
+	conn     *pgx.Conn
+	watchers []chan bool
+
+	state *acsync.StateMachine
+	bgWG  sync.WaitGroup
+	stop  chan any
+}
+
+// A LeaderElectorOption configures a [LeaderElector].
+type LeaderElectorOption func(*LeaderElector)
+
+// WithElectionInterval sets how often a follower tries to become the leader, and the leader checks its connection.
+// Defaults to 5 seconds.
+func WithElectionInterval(interval time.Duration) LeaderElectorOption {
+	return func(e *LeaderElector) {
+		e.interval = interval
+	}
+}
+
+// NewLeaderElector creates a [LeaderElector] for the election called name. Every replica taking part in the same
+// election must use the same name.
+func NewLeaderElector(logger slog.Logger, db postgres.DB, name string, opts ...LeaderElectorOption) *LeaderElector {
+	e := LeaderElector{
+		logger:   logger.With(labelElection, name),
+		db:       db,
+		name:     name,
+		key:      postgres.LockKey(name),
+		interval: defaultLeaderElectionInterval,
+		stop:     make(chan any),
+		state: acsync.NewStateMachineBuilder(logger).
+			WithComponentName("leader_elector").
+			WithIgnoreAlreadyAtEndError(true).
+			Build(),
+	}
+
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	return &e
+}
+
+// Start campaigns for leadership right away, and then periodically in a background goroutine until shut down.
+func (e *LeaderElector) Start(ctx context.Context) error {
+	return e.state.Start(ctx, func() error {
+		e.campaign(ctx)
+
+		e.bgWG.Add(1)
+		go func() {
+			defer e.bgWG.Done()
+
+			ticker := time.NewTicker(e.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-e.stop:
+					return
+				case <-ticker.C:
+					e.campaign(context.Background())
+				}
+			}
+		}()
+
+		return nil
+	})
+}
+
+// IsLeader returns true while this replica is the leader.
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// LeadershipChanges returns a channel receiving true when this replica becomes the leader, and false when it stops
+// being the leader. Only the latest change is buffered. The channel is closed when the elector is shut down.
+func (e *LeaderElector) LeadershipChanges() <-chan bool {
+	watcher := make(chan bool, 1)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	select {
+	case <-e.stop:
+		close(watcher)
+	default:
+		e.watchers = append(e.watchers, watcher)
+	}
+
+	return watcher
+}
+
+// Shutdown stops campaigning and gives up the leadership, if held.
+func (e *LeaderElector) Shutdown(ctx context.Context) error {
+	return e.state.Shutdown(ctx, func() error {
+		close(e.stop)
+		e.bgWG.Wait()
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		var err error
+		if e.conn != nil {
+			if _, unlockErr := e.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", e.key); unlockErr != nil {
+				err = fmt.Errorf("could not give up leadership of '%s': %w", e.name, unlockErr)
+			}
+			_ = e.conn.Close(ctx)
+			e.conn = nil
+		}
+
+		e.setLeader(ctx, false)
+
+		for _, watcher := range e.watchers {
+			close(watcher)
+		}
+		e.watchers = nil
+
+		return err
+	})
+}
+
+// campaign tries to take the lock as a follower, and checks the connection still holding it as the leader.
+func (e *LeaderElector) campaign(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		conn, err := e.db.Acquire(ctx)
+		if err != nil {
+			e.logger.WithError(err).WarnContext(ctx, "Problem getting a connection for leader election")
+			return
+		}
+		// The connection leaves the pool, since the lock is held for as long as the session lives.
+		e.conn = conn.Hijack()
+	}
+
+	if e.IsLeader() {
+		if _, err := e.conn.Exec(ctx, "SELECT 1"); err != nil {
+			e.logger.WithError(err).WarnContext(ctx, "Lost the leader election connection")
+			e.dropConn(ctx)
+		}
+		return
+	}
+
+	var locked bool
+	if err := e.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&locked); err != nil {
+		e.logger.WithError(err).WarnContext(ctx, "Problem campaigning for leadership")
+		e.dropConn(ctx)
+		return
+	}
+
+	e.setLeader(ctx, locked)
+}
+
+// dropConn closes the connection, which gives up the lock if it is held. mu must be held.
+func (e *LeaderElector) dropConn(ctx context.Context) {
+	_ = e.conn.Close(context.WithoutCancel(ctx))
+	e.conn = nil
+	e.setLeader(ctx, false)
+}
+
+// setLeader records the leadership, and notifies the watchers if it changed. mu must be held.
+func (e *LeaderElector) setLeader(ctx context.Context, leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+
+	if leader {
+		e.logger.InfoContext(ctx, "Became the leader")
+	} else {
+		e.logger.InfoContext(ctx, "Stopped being the leader")
+	}
+
+	for _, watcher := range e.watchers {
+		// Replace a change that was not received yet, so watchers always see the latest one.
+		select {
+		case <-watcher:
+		default:
+		}
+		watcher <- leader
+	}
+}
@@ -0,0 +1,308 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"sync"
+	"time"
+)
+
+const (
+	// subscriberBufferSize is how many notifications are buffered for a subscriber before they are dropped.
+	subscriberBufferSize = 64
+	// listenerReconnectWaitTime is the wait before reconnecting the listener connection after a failure.
+	listenerReconnectWaitTime = time.Second
+
+	labelChannel = "channel"
+)
+
+type subscriber chan postgres.Notification
+
+// A listener multiplexes the subscribers of every channel onto a single dedicated connection, which is held
+// outside the pool since it spends most of its time waiting for notifications.
+type listener struct {
+	// mu guards the fields below
+	mu          sync.Mutex
+	subscribers map[string]map[subscriber]struct{}
+	started     bool
+	closed      bool
+	// dirty is set when the channels to LISTEN on changed since the connection last caught up
+	dirty bool
+	// interrupt cancels the current wait for a notification, so the connection can catch up
+	interrupt context.CancelFunc
+}
+
+func (db *db) Subscribe(ctx context.Context, channel string) (<-chan postgres.Notification, error) {
+	if acstrings.IsBlank(channel) {
+		return nil, errors.New("channel must not be blank")
+	}
+
+	if db.connConfig == nil {
+		return nil, errors.New("database is not connected")
+	}
+
+	l := &db.listener
+	sub := make(subscriber, subscriberBufferSize)
+
+	// The background goroutines are added to bgWG while mu is held and the listener is not closed, since Shutdown
+	// closes it under mu before waiting for them.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, errors.New("database is shut down")
+	}
+
+	if l.subscribers == nil {
+		l.subscribers = map[string]map[subscriber]struct{}{}
+	}
+	if l.subscribers[channel] == nil {
+		l.subscribers[channel] = map[subscriber]struct{}{}
+	}
+	l.subscribers[channel][sub] = struct{}{}
+	l.markDirty()
+
+	if !l.started {
+		l.started = true
+		db.bgWG.Add(1)
+		go db.listen()
+	}
+
+	db.bgWG.Add(1)
+	go func() {
+		defer db.bgWG.Done()
+
+		select {
+		case <-ctx.Done():
+		case <-db.stop:
+		}
+
+		l.unsubscribe(channel, sub)
+	}()
+
+	return sub, nil
+}
+
+func (db *db) Notify(ctx context.Context, channel, payload string) error {
+	_, err := execContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+func (l *listener) unsubscribe(channel string, sub subscriber) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.subscribers[channel][sub]; !ok {
+		return
+	}
+
+	delete(l.subscribers[channel], sub)
+	if len(l.subscribers[channel]) == 0 {
+		delete(l.subscribers, channel)
+		l.markDirty()
+	}
+	close(sub)
+}
+
+// markDirty makes the connection catch up with the subscribed channels. mu must be held.
+func (l *listener) markDirty() {
+	l.dirty = true
+	if l.interrupt != nil {
+		l.interrupt()
+	}
+}
+
+// listen runs the listener connection until the database is shut down, reconnecting and listening on every
+// subscribed channel again after a failure.
+func (db *db) listen() {
+	defer db.bgWG.Done()
+
+	l := &db.listener
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-db.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var (
+		conn      *pgx.Conn
+		listening map[string]bool
+	)
+
+	defer func() {
+		if conn != nil {
+			_ = conn.Close(context.Background())
+		}
+		l.close()
+	}()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		default:
+		}
+
+		if conn == nil {
+			var err error
+			conn, err = db.connectListener(ctx)
+			if err != nil {
+				db.logger.WithError(err).WarnContext(ctx, "Problem connecting the notification listener")
+				conn = nil
+				if !db.waitOrStop(listenerReconnectWaitTime) {
+					return
+				}
+				continue
+			}
+			listening = map[string]bool{}
+			db.logger.DebugContext(ctx, "Notification listener connected")
+		}
+
+		if err := l.catchUp(ctx, conn, listening); err != nil {
+			db.logger.WithError(err).WarnContext(ctx, "Problem listening on notification channels; reconnecting")
+			_ = conn.Close(context.Background())
+			conn = nil
+			if !db.waitOrStop(listenerReconnectWaitTime) {
+				return
+			}
+			continue
+		}
+
+		waitCtx, waitCancel := context.WithCancel(ctx)
+		l.mu.Lock()
+		l.interrupt = waitCancel
+		if l.dirty {
+			waitCancel()
+		}
+		l.mu.Unlock()
+
+		notification, err := conn.WaitForNotification(waitCtx)
+		waitCancel()
+
+		if err != nil {
+			if waitCtx.Err() != nil {
+				// interrupted to catch up or stop; the connection is still usable
+				continue
+			}
+
+			db.logger.WithError(err).WarnContext(ctx, "Problem waiting for notifications; reconnecting")
+			_ = conn.Close(context.Background())
+			conn = nil
+			if !db.waitOrStop(listenerReconnectWaitTime) {
+				return
+			}
+			continue
+		}
+
+		if dropped := l.dispatch(postgres.Notification{Channel: notification.Channel, Payload: notification.Payload, PID: notification.PID}); dropped > 0 {
+			db.logger.With(labelChannel, notification.Channel).WarnContextf(ctx, "Dropped notification for subscribers that fell behind; subscribers: %d", dropped)
+		}
+	}
+}
+
+// catchUp issues LISTEN and UNLISTEN so the connection listens on exactly the subscribed channels.
+func (l *listener) catchUp(ctx context.Context, conn *pgx.Conn, listening map[string]bool) error {
+	l.mu.Lock()
+	wanted := make(map[string]bool, len(l.subscribers))
+	for channel := range l.subscribers {
+		wanted[channel] = true
+	}
+	l.dirty = false
+	l.mu.Unlock()
+
+	for channel := range wanted {
+		if listening[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("could not listen on channel '%s': %w", channel, err)
+		}
+		listening[channel] = true
+	}
+
+	for channel := range listening {
+		if wanted[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("could not unlisten on channel '%s': %w", channel, err)
+		}
+		delete(listening, channel)
+	}
+
+	return nil
+}
+
+// dispatch delivers the notification to every subscriber of its channel, dropping it for subscribers whose
+// buffer is full rather than holding up the others. It returns the number of subscribers the notification was
+// dropped for.
+func (l *listener) dispatch(notification postgres.Notification) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := 0
+	for sub := range l.subscribers[notification.Channel] {
+		select {
+		case sub <- notification:
+		default:
+			dropped++
+		}
+	}
+
+	return dropped
+}
+
+// reject makes the listener reject new subscribers, before the database waits for its background goroutines.
+func (l *listener) reject() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+}
+
+// close closes the channels of every subscriber, and rejects new ones.
+func (l *listener) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for channel, subs := range l.subscribers {
+		for sub := range subs {
+			close(sub)
+		}
+		delete(l.subscribers, channel)
+	}
+	l.closed = true
+	l.interrupt = nil
+}
+
+// connectListener opens the dedicated connection of the listener, with the current credentials of the provider.
+func (db *db) connectListener(ctx context.Context) (*pgx.Conn, error) {
+	cc := db.connConfig.Copy()
+	if err := db.applyCredentials(ctx, cc); err != nil {
+		return nil, err
+	}
+
+	return pgx.ConnectConfig(ctx, cc)
+}
+
+// waitOrStop waits for the given duration, returning false if the database is shut down in the meantime.
+func (db *db) waitOrStop(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-db.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
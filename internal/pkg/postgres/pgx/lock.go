@@ -0,0 +1,126 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"sync"
+)
+
+// sessionLock is an advisory lock held on a connection acquired from the pool. The connection is kept out of the
+// pool until the lock is released, since the lock belongs to its session.
+type sessionLock struct {
+	key  int64
+	once sync.Once
+	conn *pgxpool.Conn
+}
+
+func (l *sessionLock) Unlock(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		defer l.conn.Release()
+
+		var unlocked bool
+		if err = l.conn.QueryRow(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&unlocked); err != nil {
+			// The session may still hold the lock, so it must not go back to the pool.
+			_ = l.conn.Conn().Close(context.WithoutCancel(ctx))
+			err = postgres.TranslateError(err)
+			return
+		}
+
+		if !unlocked {
+			err = errors.New("advisory lock was not held")
+		}
+	})
+
+	return err
+}
+
+// TryLock takes the session advisory lock called name if it is available, without waiting. The returned bool is
+// false when another session holds the lock, in which case the lock is nil.
+func TryLock(ctx context.Context, db postgres.DB, name string) (postgres.SessionLock, bool, error) {
+	return doLock(ctx, db, name, "SELECT pg_try_advisory_lock($1)")
+}
+
+// Lock takes the session advisory lock called name, waiting until it is available or the context is done.
+func Lock(ctx context.Context, db postgres.DB, name string) (postgres.SessionLock, error) {
+	lock, _, err := doLock(ctx, db, name, "SELECT true FROM pg_advisory_lock($1)")
+	return lock, err
+}
+
+func doLock(ctx context.Context, db postgres.DB, name, query string) (postgres.SessionLock, bool, error) {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := postgres.LockKey(name)
+
+	var locked bool
+	if err := conn.QueryRow(ctx, query, key).Scan(&locked); err != nil {
+		// A canceled wait leaves the session in an unknown state, so the connection is not reused.
+		_ = conn.Conn().Close(context.WithoutCancel(ctx))
+		conn.Release()
+		return nil, false, fmt.Errorf("could not lock '%s': %w", name, postgres.TranslateError(err))
+	}
+
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	return &sessionLock{key: key, conn: conn}, true, nil
+}
+
+// WithLock runs f while holding the session advisory lock called name, waiting for the lock until it is available
+// or the context is done.
+func WithLock(ctx context.Context, db postgres.DB, name string, f func(context.Context) error) (err error) {
+	lock, err := Lock(ctx, db, name)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if unlockErr := lock.Unlock(context.WithoutCancel(ctx)); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not unlock '%s': %w", name, unlockErr))
+		}
+	}()
+
+	return f(ctx)
+}
+
+// TryWithLock is like [WithLock], but it returns false without running f when another session holds the lock.
+func TryWithLock(ctx context.Context, db postgres.DB, name string, f func(context.Context) error) (ran bool, err error) {
+	lock, locked, err := TryLock(ctx, db, name)
+	if err != nil || !locked {
+		return false, err
+	}
+
+	defer func() {
+		if unlockErr := lock.Unlock(context.WithoutCancel(ctx)); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not unlock '%s': %w", name, unlockErr))
+		}
+	}()
+
+	return true, f(ctx)
+}
+
+// TryLockContextTx takes the transaction advisory lock called name on the context transaction if it is available,
+// without waiting. The lock is released when the transaction ends.
+func TryLockContextTx(ctx context.Context, name string) (bool, error) {
+	var locked bool
+	if err := mustGetContextTx(ctx).QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", postgres.LockKey(name)).Scan(&locked); err != nil {
+		return false, postgres.TranslateError(err)
+	}
+
+	return locked, nil
+}
+
+// LockContextTx takes the transaction advisory lock called name on the context transaction, waiting until it is
+// available or the context is done. The lock is released when the transaction ends.
+func LockContextTx(ctx context.Context, name string) error {
+	_, err := execContext(ctx, "SELECT pg_advisory_xact_lock($1)", postgres.LockKey(name))
+	return err
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/migrate"
 	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
 	"github.com/zhughes3/go-accelerate/pkg/slog"
 	acsync "github.com/zhughes3/go-accelerate/pkg/sync"
+	"io/fs"
 	"sync"
-	"time"
 )
 
 type db struct {
@@ -18,6 +21,14 @@ type db struct {
 
 	sqldb  *pgxpool.Pool
 	config *postgres.Config
+	// connConfig is the configuration of connections to the primary, set when connecting
+	connConfig *pgx.ConnConfig
+
+	// replicas serve read-only transactions, nil when no replica hosts are configured
+	replicas *replicaSet
+
+	// listener delivers notifications to the subscribers of [db.Subscribe]
+	listener listener
 
 	// state tracks the current state of the [pgxpool.Pool], "new", "started", or "shutdown"
 	state *acsync.StateMachine
@@ -25,30 +36,98 @@ type db struct {
 	// statsMu guards currentStats
 	statsMu      sync.RWMutex
 	currentStats postgres.DBStats
+	// statsCollector exports currentStats to prometheus
+	statsCollector *postgres.StatsCollector
+	// statsRegistered is true when statsCollector was registered by this database, rather than by an earlier one
+	// exporting the same metrics, so shutdown only unregisters its own
+	statsRegistered bool
 
-	// bgWG
+	// bgWG tracks the background goroutines, so shutdown can wait for them to terminate
 	bgWG sync.WaitGroup
 	// stop is a channel used to terminate background goroutines
 	stop chan any
+
+	// migrations are applied on connect when [postgres.Config.MigrateOnConnect] is set
+	migrations  fs.FS
+	migrateOpts []migrate.Option
+
+	// sessionSettings are set locally at the start of every transaction
+	sessionSettings []sessionSetting
+
+	// queries are validated on connect by preparing them
+	queries []*postgres.QueryRegistry
+
+	// credentials are set on every new connection, and checked periodically to recycle connections when they change
+	credentials postgres.CredentialProvider
+
+	// attempts counts the attempts to connect and acquire connections, by operation and outcome
+	attempts *prometheus.CounterVec
+}
+
+// An Option configures the database created by [NewDB].
+type Option func(*db)
+
+// WithMigrations sets the goose migrations applied when connecting, if [postgres.Config.MigrateOnConnect] is set.
+func WithMigrations(fsys fs.FS, opts ...migrate.Option) Option {
+	return func(db *db) {
+		db.migrations = fsys
+		db.migrateOpts = opts
+	}
 }
 
-func NewDB(logger slog.Logger, config *postgres.Config) *db {
+func NewDB(logger slog.Logger, config *postgres.Config, opts ...Option) *db {
 	_db := db{
-		logger: logger,
-		config: config,
-		stop:   make(chan any),
+		logger:      logger,
+		config:      config,
+		credentials: config.CredentialProvider(),
+		stop:        make(chan any),
 		state: acsync.NewStateMachineBuilder(logger).
 			WithComponentName("pgx_database").
 			WithIgnoreAlreadyAtEndError(true).
 			Build(),
 	}
+	_db.statsCollector = postgres.NewStatsCollector(_db.Stats, config.Subsystem)
+	_db.attempts = postgres.RegisterConnectionAttempts(logger, config.Subsystem)
+
+	for _, opt := range opts {
+		opt(&_db)
+	}
 
 	return &_db
 }
 
-func NewDBConnect(ctx context.Context, logger slog.Logger, config *postgres.Config) (postgres.DB, error) {
-	_db := NewDB(logger, config)
-	return _db, _db.Connect(ctx)
+func NewDBConnect(ctx context.Context, logger slog.Logger, config *postgres.Config, opts ...Option) (postgres.DB, error) {
+	_db := NewDB(logger, config, opts...)
+	if err := _db.Connect(ctx); err != nil {
+		return _db, err
+	}
+
+	if err := _db.migrate(ctx); err != nil {
+		return _db, err
+	}
+
+	return _db, _db.validateQueries(ctx)
+}
+
+// migrate applies the pending migrations when migrating on connect is enabled.
+func (db *db) migrate(ctx context.Context) error {
+	if !db.config.MigrateOnConnect || db.migrations == nil {
+		return nil
+	}
+
+	migrator, err := migrate.NewMigrator(db.logger, db, db.migrations, db.migrateOpts...)
+	if err != nil {
+		return err
+	}
+
+	db.logger.InfoContext(ctx, "Migrating the database")
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("migrating the database: %w", err)
+	}
+	db.logger.InfoContextf(ctx, "Migrating the database...complete; applied: %d", len(applied))
+
+	return nil
 }
 
 func (db *db) Connect(ctx context.Context) error {
@@ -68,70 +147,68 @@ func (db *db) doConnect(ctx context.Context) error {
 		return err
 	}
 
-	cpc := db.config.NewPoolConfig(cc)
-
-	for {
-		if db.sqldb == nil {
-			db.logger.InfoContext(ctx, "Attempting to connect to the database")
-			if err := db.connect(ctx, cpc); err != nil {
-				db.logger.WithError(err).ErrorContext(ctx, "Problem connecting to the database")
-			}
-			if db.sqldb == nil {
-				db.logger.InfoContext(ctx, "Sleeping...")
-				db.sleep()
-				continue
-			}
-			db.logger.With("Database", db.config.Host).InfoContext(ctx, "Database connected")
-		}
-		break
-	}
+	db.connConfig = cc
+	cpc := db.newPoolConfig(cc)
 
-	// TODO collect stats
+	policy := db.reportingAttempts(operationConnect, db.config.ConnectRetryPolicy())
+	pool, err := retry.DoValue(ctx, policy, func(ctx context.Context) (*pgxpool.Pool, error) {
+		db.logger.InfoContext(ctx, "Attempting to connect to the database")
+		return db.connect(ctx, cpc)
+	})
+	db.countAttempt(operationConnect, err)
+	if err != nil {
+		return acerrors.Wrap(err, "could not connect to the database")
+	}
 
-	return nil
-}
+	db.sqldb = pool
+	db.logger.With("Database", db.config.Host).InfoContext(ctx, "Database connected")
 
-func (db *db) connect(ctx context.Context, config *pgxpool.Config) error {
-	pool, err := pgxpool.NewWithConfig(ctx, config)
-	if err != nil {
+	if err := db.connectReplicas(ctx, cc); err != nil {
 		return err
 	}
 
-	db.sqldb = pool
+	db.startStatsCollection(ctx)
+	db.startCredentialRefresh(ctx)
 
 	return nil
 }
 
-func (db *db) sleep() {
-	time.Sleep(time.Duration(db.config.ConnectionRetryWaitTime) * time.Second)
-}
+// connect creates the pool and pings the database, since the pool only opens connections when they are needed.
+// The pool opens its minimum connections in the background with the context it is created with, so it is created
+// with one that is not canceled along with ctx, e.g. when the caller stops waiting once connected.
+func (db *db) connect(ctx context.Context, config *pgxpool.Config) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.NewWithConfig(context.WithoutCancel(ctx), config)
+	if err != nil {
+		return nil, err
+	}
 
-func (db *db) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
-	return db.doAcquire(ctx, db.config.ConnectionRetries)
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
 }
 
-func (db *db) doAcquire(ctx context.Context, retries int) (*pgxpool.Conn, error) {
-	conn, err := db.sqldb.Acquire(ctx)
+func (db *db) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	policy := db.reportingAttempts(operationAcquire, db.config.AcquireRetryPolicy())
+	conn, err := retry.DoValue(ctx, policy, db.sqldb.Acquire)
+	db.countAttempt(operationAcquire, err)
 	if err != nil {
-		if retries == 0 {
-			return nil, acerrors.Wrap(err, "database unavailable")
-		}
-
-		db.logger.WithError(err).WarnContext(ctx, "Problem getting database connection; retry: %d", db.config.ConnectionRetries-retries+1)
-		return db.doAcquire(ctx, retries-1)
+		return nil, acerrors.Wrap(err, "database unavailable")
 	}
 
 	return conn, nil
 }
 
 func (db *db) Run(ctx context.Context, f func(*pgxpool.Conn) error) error {
-	//TODO implement me
-	panic("implement me")
-}
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
 
-func (db *db) Transaction(ctx context.Context, f func(pgx.Tx) error) error {
-	//TODO implement me
-	panic("implement me")
+	return f(conn)
 }
 
 func (db *db) ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
@@ -150,18 +227,48 @@ func (db *db) BeginContextTx(ctx context.Context) (context.Context, bool, error)
 }
 
 func (db *db) doBeginContextTx(ctx context.Context, options pgx.TxOptions) (context.Context, bool, error) {
-	if _, ok := getContextTx(ctx); ok {
-		return ctx, false, nil
+	if tx, ok := getContextTx(ctx); ok {
+		if !db.nestedTxEnabled(ctx) {
+			return ctx, false, nil
+		}
+
+		return db.beginSavepoint(ctx, tx)
 	}
 
-	tx, err := db.sqldb.BeginTx(ctx, options)
+	tx, err := db.beginPool(ctx, options).BeginTx(ctx, options)
 	if err != nil {
 		return nil, false, fmt.Errorf("could not begin tx: %w", err)
 	}
 
-	return context.WithValue(ctx, txContextKey, tx), true, nil
+	if err := db.applySessionSettings(ctx, tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, false, err
+	}
+
+	return contextWithTx(ctx, tx, 1), true, nil
+}
+
+func (db *db) nestedTxEnabled(ctx context.Context) bool {
+	return db.config.EnableNestedTx || postgres.IsNestedTxContext(ctx)
 }
 
+// beginSavepoint creates a SAVEPOINT on the outer transaction. The returned context carries the savepoint as its
+// transaction, so committing or rolling it back releases or rolls back to the savepoint.
+func (db *db) beginSavepoint(ctx context.Context, tx pgx.Tx) (context.Context, bool, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not create savepoint: %w", err)
+	}
+
+	ctx = contextWithTx(ctx, savepoint, TxDepth(ctx)+1)
+	db.logger.With(labelTxDepth, TxDepth(ctx)).DebugContext(ctx, "Created savepoint")
+
+	return ctx, true, nil
+}
+
+// BeginContextReadOnlyTx is like [db.BeginContextTx], but a new transaction is read-only and begins on a replica
+// when one is healthy and fresh enough for the context. Read-only only applies to new transactions: the transaction
+// joined on the context, or the savepoint created on it in nested mode, is as writable as the outer transaction.
 func (db *db) BeginContextReadOnlyTx(ctx context.Context) (context.Context, bool, error) {
 	return db.doBeginContextTx(ctx, pgx.TxOptions{
 		AccessMode: pgx.ReadOnly,
@@ -171,6 +278,10 @@ func (db *db) BeginContextReadOnlyTx(ctx context.Context) (context.Context, bool
 func (db *db) CloseContextTx(ctx context.Context, err error) error {
 	tx := mustGetContextTx(ctx)
 
+	if depth := TxDepth(ctx); depth > 1 {
+		db.logger.With(labelTxDepth, depth).WithError(err).DebugContext(ctx, "Closing savepoint")
+	}
+
 	if err == nil {
 		if commitErr := tx.Commit(ctx); commitErr != nil {
 			return fmt.Errorf("could not commit tx: %w", commitErr)
@@ -185,21 +296,28 @@ func (db *db) CloseContextTx(ctx context.Context, err error) error {
 }
 
 func (db *db) NewBatch(statements []postgres.BatchStatement) *pgx.Batch {
-	//TODO implement me
-	panic("implement me")
+	return newBatch(statements)
 }
 
 func (db *db) Shutdown(ctx context.Context) error {
 	return db.state.Shutdown(ctx, func() error {
 		db.logger.InfoContext(ctx, "Shutting down the database...")
 
-		// Tell all background goroutines to stop and terminate.
+		// Stop new subscriptions from starting background goroutines, then tell all of them to stop and terminate.
+		db.listener.reject()
 		close(db.stop)
 
 		// Wait for all background goroutines to complete.
 		db.bgWG.Wait()
 
+		if db.statsRegistered {
+			prometheus.Unregister(db.statsCollector)
+		}
+
 		// Now that all background processes have been terminated, the database can be safely closed.
+		if db.replicas != nil {
+			db.replicas.close()
+		}
 		db.sqldb.Close()
 		db.logger.InfoContext(ctx, "Shutting down the database...complete")
 
@@ -212,14 +330,56 @@ func (db *db) SecurityString() [32]byte {
 }
 
 func (db *db) Stats() postgres.DBStats {
-	//TODO implement me
-	panic("implement me")
+	db.statsMu.RLock()
+	defer db.statsMu.RUnlock()
+
+	return db.currentStats
 }
 
-const txContextKey = contextKey("PGX_TX")
+const (
+	txContextKey      = contextKey("PGX_TX")
+	txDepthContextKey = contextKey("PGX_TX_DEPTH")
+)
+
+const labelTxDepth = "txDepth"
 
 type contextKey string
 
+// ContextWithTx returns a context carrying the transaction as the context transaction of the query helpers, such as
+// [ScanAllContext], one level deeper than the one already on the context. It is meant for fakes of [postgres.DB],
+// whose transactions do not come from a pool.
+func ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return contextWithTx(ctx, tx, TxDepth(ctx)+1)
+}
+
+func contextWithTx(ctx context.Context, tx pgx.Tx, depth int) context.Context {
+	return context.WithValue(context.WithValue(ctx, txContextKey, tx), txDepthContextKey, depth)
+}
+
+// TxDepth returns how deeply nested the transaction on the context is: 0 when there is no transaction,
+// 1 for a transaction, and 2 or more for savepoints created in nested mode. See [postgres.ContextWithNestedTx].
+func TxDepth(ctx context.Context) int {
+	if depth, ok := ctx.Value(txDepthContextKey).(int); ok {
+		return depth
+	}
+
+	if _, ok := getContextTx(ctx); ok {
+		return 1
+	}
+
+	return 0
+}
+
+// TxDepthExtractor is a logger context extractor that labels log entries with the depth of the context transaction.
+func TxDepthExtractor(ctx context.Context) map[string]any {
+	depth := TxDepth(ctx)
+	if depth == 0 {
+		return nil
+	}
+
+	return map[string]any{labelTxDepth: depth}
+}
+
 func getContextTx(ctx context.Context) (pgx.Tx, bool) {
 	tx, ok := ctx.Value(txContextKey).(pgx.Tx)
 	return tx, ok
@@ -0,0 +1,50 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+)
+
+// WithQueries sets the query registries validated when connecting with [NewDBConnect], after any migrations.
+func WithQueries(registries ...*postgres.QueryRegistry) Option {
+	return func(db *db) {
+		db.queries = append(db.queries, registries...)
+	}
+}
+
+// ValidateQueries prepares every query of the registries against the database, so a query that does not parse, or
+// refers to a missing table or column, fails at startup rather than when it is first run. All failures are returned.
+func ValidateQueries(ctx context.Context, db postgres.DB, registries ...*postgres.QueryRegistry) error {
+	return db.Run(ctx, func(conn *pgxpool.Conn) error {
+		var errs []error
+
+		for _, registry := range registries {
+			for _, q := range registry.Queries() {
+				// The unnamed statement is replaced by the next one, so nothing is left behind on the connection.
+				if _, err := conn.Conn().PgConn().Prepare(ctx, "", q.SQL, nil); err != nil {
+					errs = append(errs, fmt.Errorf("query '%s' in '%s' is invalid: %w", q.Name, q.Source, err))
+				}
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// validateQueries validates the queries set with [WithQueries].
+func (db *db) validateQueries(ctx context.Context) error {
+	if len(db.queries) == 0 {
+		return nil
+	}
+
+	db.logger.InfoContext(ctx, "Validating the database queries")
+	if err := ValidateQueries(ctx, db, db.queries...); err != nil {
+		return fmt.Errorf("validating the database queries: %w", err)
+	}
+	db.logger.InfoContext(ctx, "Validating the database queries...complete")
+
+	return nil
+}
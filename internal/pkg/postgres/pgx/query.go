@@ -6,6 +6,7 @@ import (
 	"github.com/georgysavva/scany/v2/pgxscan"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
 	"github.com/zhughes3/go-accelerate/pkg/slices"
 )
 
@@ -14,7 +15,7 @@ type RowMapper[T any] func(pgx.Rows) (T, error)
 func ExecInsertContextForPrimaryKey(ctx context.Context, query string, args ...any) (string, error) {
 	var id string
 	if err := mustGetContextTx(ctx).QueryRow(ctx, query, args...).Scan(&id); err != nil {
-		return "", err
+		return "", postgres.TranslateError(err)
 	}
 
 	return id, nil
@@ -36,7 +37,8 @@ func ExecDeleteContext(ctx context.Context, query string, args ...any) (bool, er
 }
 
 func RowsContext(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
-	return mustGetContextTx(ctx).Query(ctx, query, args...)
+	rows, err := mustGetContextTx(ctx).Query(ctx, query, args...)
+	return rows, postgres.TranslateError(err)
 }
 
 func QueryRowContext[T any](ctx context.Context, mapRow RowMapper[T], query string, args ...any) (T, bool, error) {
@@ -52,7 +54,7 @@ func QueryRowContext[T any](ctx context.Context, mapRow RowMapper[T], query stri
 func QueryContext[T any](ctx context.Context, mapRow RowMapper[T], query string, args ...any) ([]T, error) {
 	rows, err := mustGetContextTx(ctx).Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, postgres.TranslateError(err)
 	}
 	defer rows.Close()
 	var entities []T
@@ -66,7 +68,7 @@ func QueryContext[T any](ctx context.Context, mapRow RowMapper[T], query string,
 	}
 
 	if rows.Err() != nil {
-		return nil, rows.Err()
+		return nil, postgres.TranslateError(rows.Err())
 	}
 
 	return entities, nil
@@ -79,7 +81,7 @@ func ScanOneContext[T any](ctx context.Context, query string, args ...any) (T, b
 			err = nil
 		}
 
-		return t, false, err
+		return t, false, postgres.TranslateError(err)
 	}
 
 	return t, true, nil
@@ -98,7 +100,7 @@ func ScanAndMapOneContext[T, S any](ctx context.Context, mapper func(T) S, query
 func ScanAllContext[T any](ctx context.Context, query string, args ...any) ([]T, error) {
 	var t []T
 	if err := pgxscan.Select(ctx, mustGetContextTx(ctx), &t, query, args...); err != nil {
-		return nil, err
+		return nil, postgres.TranslateError(err)
 	}
 
 	return t, nil
@@ -116,7 +118,7 @@ func ScanAndMapAllContext[T, S any](ctx context.Context, mapper func(T) S, query
 func execContext(ctx context.Context, query string, args ...any) (int, error) {
 	ct, err := mustGetContextTx(ctx).Exec(ctx, query, args...)
 	if err != nil || ct.RowsAffected() == 0 {
-		return 0, err
+		return 0, postgres.TranslateError(err)
 	}
 
 	return int(ct.RowsAffected()), nil
@@ -0,0 +1,245 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultReplicaHealthCheckInterval = 5 * time.Second
+
+	labelReplica = "replica"
+	labelLag     = "lag"
+
+	// replicaStatusQuery returns the replayed WAL position of a replica and how far it lags behind the primary.
+	// A replica that has replayed everything it received is not lagging, however long ago the last write was.
+	replicaStatusQuery = `SELECT
+		COALESCE(pg_last_wal_replay_lsn(), pg_current_wal_lsn())::text,
+		CASE WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`
+)
+
+type replica struct {
+	host string
+	pool *pgxpool.Pool
+
+	// mu guards healthy, lsn and lag
+	mu      sync.RWMutex
+	healthy bool
+	lsn     postgres.LSN
+	lag     time.Duration
+}
+
+func (r *replica) status() (bool, postgres.LSN, time.Duration) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.healthy, r.lsn, r.lag
+}
+
+// A replicaSet routes read-only transactions to the healthy replicas.
+type replicaSet struct {
+	replicas  []*replica
+	selection string
+	maxLag    time.Duration
+
+	// next is the round-robin counter
+	next atomic.Uint64
+}
+
+// connectReplicas creates a pool for each replica host, checks their health, and keeps checking it in a
+// background goroutine until the database is shut down.
+func (db *db) connectReplicas(ctx context.Context, cc *pgx.ConnConfig) error {
+	if len(db.config.ReplicaHosts) == 0 {
+		return nil
+	}
+
+	selection := db.config.ReplicaSelection
+	if acstrings.IsBlank(selection) {
+		selection = postgres.ReplicaSelectionRoundRobin
+	}
+
+	if selection != postgres.ReplicaSelectionRoundRobin && selection != postgres.ReplicaSelectionLeastConnections {
+		return fmt.Errorf("'%s' is not a valid replica selection", selection)
+	}
+
+	set := replicaSet{
+		selection: selection,
+		maxLag:    db.config.ReplicaMaxLag,
+	}
+
+	for _, hostPort := range db.config.ReplicaHosts {
+		rcc, err := replicaConnConfig(cc, hostPort)
+		if err != nil {
+			return err
+		}
+
+		// like the primary pool, see connect
+		pool, err := pgxpool.NewWithConfig(context.WithoutCancel(ctx), db.newPoolConfig(rcc))
+		if err != nil {
+			set.close()
+			return fmt.Errorf("could not create replica pool for '%s': %w", hostPort, err)
+		}
+
+		set.replicas = append(set.replicas, &replica{host: hostPort, pool: pool})
+	}
+
+	db.replicas = &set
+	db.checkReplicas(ctx)
+
+	interval := defaultReplicaHealthCheckInterval
+	if db.config.ReplicaHealthCheckInterval > 0 {
+		interval = db.config.ReplicaHealthCheckInterval
+	}
+
+	db.bgWG.Add(1)
+	go func() {
+		defer db.bgWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-db.stop:
+				return
+			case <-ticker.C:
+				db.checkReplicas(context.Background())
+			}
+		}
+	}()
+
+	return nil
+}
+
+func replicaConnConfig(cc *pgx.ConnConfig, hostPort string) (*pgx.ConnConfig, error) {
+	rcc := cc.Copy()
+	rcc.Host = hostPort
+	// A replica must not fall back to the hosts of the primary.
+	rcc.Fallbacks = nil
+
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid replica host: %w", hostPort, err)
+		}
+		rcc.Host = host
+		rcc.Port = uint16(p)
+	}
+
+	if rcc.TLSConfig != nil {
+		rcc.TLSConfig.ServerName = rcc.Host
+	}
+
+	return rcc, nil
+}
+
+func (db *db) checkReplicas(ctx context.Context) {
+	for _, r := range db.replicas.replicas {
+		db.checkReplica(ctx, r)
+	}
+}
+
+func (db *db) checkReplica(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, defaultReplicaHealthCheckInterval)
+	defer cancel()
+
+	var (
+		rawLSN     string
+		lagSeconds float64
+	)
+	err := r.pool.QueryRow(ctx, replicaStatusQuery).Scan(&rawLSN, &lagSeconds)
+
+	var lsn postgres.LSN
+	if err == nil {
+		lsn, err = postgres.ParseLSN(rawLSN)
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	healthy := err == nil && (db.replicas.maxLag <= 0 || lag <= db.replicas.maxLag)
+
+	r.mu.Lock()
+	wasHealthy := r.healthy
+	r.healthy, r.lsn, r.lag = healthy, lsn, lag
+	r.mu.Unlock()
+
+	logger := db.logger.With(labelReplica, r.host).With(labelLag, lag)
+	switch {
+	case err != nil && wasHealthy:
+		logger.WithError(err).WarnContext(ctx, "Replica is unhealthy")
+	case err == nil && !healthy && wasHealthy:
+		logger.WarnContext(ctx, "Replica is lagging too far behind the primary")
+	case healthy && !wasHealthy:
+		logger.InfoContext(ctx, "Replica is healthy")
+	}
+}
+
+// pick returns the pool of a healthy replica fresh enough for the context, or nil when there is none.
+// See [postgres.ContextWithMinLSN] and [postgres.ContextWithMaxReplicaLag].
+func (s *replicaSet) pick(ctx context.Context) *pgxpool.Pool {
+	minLSN, checkLSN := postgres.MinLSN(ctx)
+	maxLag, checkLag := postgres.MaxReplicaLag(ctx)
+
+	candidates := make([]*replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		healthy, lsn, lag := r.status()
+		if !healthy || checkLSN && lsn < minLSN || checkLag && lag > maxLag {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if s.selection == postgres.ReplicaSelectionLeastConnections {
+		return slices.MinFunc(candidates, func(a, b *replica) int {
+			return int(a.pool.Stat().AcquiredConns() - b.pool.Stat().AcquiredConns())
+		}).pool
+	}
+
+	return candidates[s.next.Add(1)%uint64(len(candidates))].pool
+}
+
+func (s *replicaSet) close() {
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+// beginPool returns the pool a transaction with the options should begin on: a replica for read-only transactions
+// when one is available, the primary otherwise.
+func (db *db) beginPool(ctx context.Context, options pgx.TxOptions) *pgxpool.Pool {
+	if db.replicas == nil || options.AccessMode != pgx.ReadOnly {
+		return db.sqldb
+	}
+
+	if pool := db.replicas.pick(ctx); pool != nil {
+		return pool
+	}
+
+	db.logger.DebugContext(ctx, "No replica available for read-only transaction; using the primary")
+
+	return db.sqldb
+}
+
+func (db *db) CurrentLSN(ctx context.Context) (postgres.LSN, error) {
+	var rawLSN string
+	if err := db.sqldb.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&rawLSN); err != nil {
+		return 0, postgres.TranslateError(err)
+	}
+
+	return postgres.ParseLSN(rawLSN)
+}
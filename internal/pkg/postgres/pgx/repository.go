@@ -0,0 +1,381 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
+	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Options of the `db` struct tag understood by [Repository], e.g. `db:"id,pk,generated"`.
+const (
+	// tagPrimaryKey marks the primary key column.
+	tagPrimaryKey = "pk"
+	// tagGenerated marks a column set by the database, e.g. by a default or a trigger, which is never written.
+	tagGenerated = "generated"
+	// tagVersion marks the timestamp column checked and bumped by updates, for optimistic concurrency.
+	tagVersion = "version"
+	// tagSoftDelete marks the nullable timestamp column set when a row is soft deleted.
+	tagSoftDelete = "soft_delete"
+)
+
+type repositoryField struct {
+	copyField
+	primaryKey bool
+	generated  bool
+}
+
+// A Repository runs the common statements of a table, whose rows are structs of type T with a primary key of type
+// ID, on the context transaction. Columns are mapped to the fields of T like [ScanAllContext] maps them, and the
+// options of their `db` tags describe how the columns are written:
+//
+//	type Timeline struct {
+//		ID        string     `db:"id,pk,generated"`
+//		Name      string     `db:"title"`
+//		CreatedAt time.Time  `db:"created_at"`
+//		UpdatedAt time.Time  `db:"updated_at,version"`
+//		DeletedAt *time.Time `db:"deleted_at,soft_delete"`
+//	}
+//
+// Rows are returned with every column, so the values generated by the database, such as the IDs set by
+// generate_primary_key_trigger, are filled in by inserts and updates. Soft deleted rows are left out of every
+// statement other than [Repository.Delete].
+type Repository[T any, ID comparable] struct {
+	table      string
+	fields     []repositoryField
+	columns    []string
+	primaryKey repositoryField
+	version    *repositoryField
+	softDelete *repositoryField
+}
+
+// NewRepository creates a [Repository] of the table, returning an error when T is not a struct with a primary key.
+func NewRepository[T any, ID comparable](table string) (*Repository[T, ID], error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	copyFields, err := copyFieldsOf(t, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := Repository[T, ID]{table: table}
+	foundPrimaryKey := false
+
+	for _, cf := range copyFields {
+		tag := t.FieldByIndex(cf.index).Tag.Get("db")
+		options := strings.Split(tag, ",")[1:]
+
+		f := repositoryField{
+			copyField:  cf,
+			primaryKey: slices.Contains(options, tagPrimaryKey),
+			generated:  slices.Contains(options, tagGenerated),
+		}
+
+		r.fields = append(r.fields, f)
+		r.columns = append(r.columns, f.column)
+
+		switch {
+		case f.primaryKey:
+			r.primaryKey, foundPrimaryKey = f, true
+		case slices.Contains(options, tagVersion):
+			r.version = &f
+		case slices.Contains(options, tagSoftDelete):
+			r.softDelete = &f
+		}
+	}
+
+	if !foundPrimaryKey {
+		return nil, fmt.Errorf("%s has no field tagged as the primary key of %s", t, table)
+	}
+
+	return &r, nil
+}
+
+// MustNewRepository is like [NewRepository], but panics on error. It is meant for package variables.
+func MustNewRepository[T any, ID comparable](table string) *Repository[T, ID] {
+	r, err := NewRepository[T, ID](table)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// Get returns the row with the ID, and false when there is none.
+func (r *Repository[T, ID]) Get(ctx context.Context, id ID) (T, bool, error) {
+	query, args, err := postgres.Select(r.columns...).
+		Named(r.queryName("Get")).
+		From(r.table).
+		Where(postgres.Cond(r.primaryKey.column+" = ?", id), r.notDeleted()).
+		Build()
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+
+	return ScanOneContext[T](ctx, query, args...)
+}
+
+// List returns the rows matching the predicates, e.g. postgres.Cond("user_id = ?", userID).
+func (r *Repository[T, ID]) List(ctx context.Context, predicates ...postgres.Predicate) ([]T, error) {
+	query, args, err := r.selectWhere("List", predicates).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return ScanAllContext[T](ctx, query, args...)
+}
+
+// ListPage returns the page of the rows matching the predicates. The columns of the [pagination.Spec] of the page
+// are the columns of the table.
+func (r *Repository[T, ID]) ListPage(ctx context.Context, page pagination.Page, predicates ...postgres.Predicate) ([]T, error) {
+	query, args, err := r.selectWhere("ListPage", predicates).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	query, args = page.Query(query, args...)
+	return ScanAllContext[T](ctx, query, args...)
+}
+
+// Insert inserts the row, leaving the generated columns and the version column to the database, which must have a
+// default for it, e.g. DEFAULT NOW(), and returns the row as inserted.
+func (r *Repository[T, ID]) Insert(ctx context.Context, row T) (T, error) {
+	fields := r.writableFields()
+
+	query, args, err := postgres.InsertInto(r.table).
+		Named(r.queryName("Insert")).
+		Columns(columnsOf(fields)...).
+		Values(valuesOf(row, fields)...).
+		Returning(r.columns...).
+		Build()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	inserted, _, err := ScanOneContext[T](ctx, query, args...)
+	return inserted, err
+}
+
+// Update writes the row over the one with its ID, and returns the row as updated. With a version column, the row is
+// only updated if its version has not changed since it was read, and the version is bumped. An
+// [acerrors.ConflictError] is returned otherwise, and an [acerrors.NotFoundError] when there is no row with the ID.
+func (r *Repository[T, ID]) Update(ctx context.Context, row T) (T, error) {
+	var zero T
+
+	v := reflect.ValueOf(row)
+	id := fieldValue(v, r.primaryKey.copyField)
+
+	b := postgres.Update(r.table).
+		Named(r.queryName("Update")).
+		Where(postgres.Cond(r.primaryKey.column+" = ?", id), r.notDeleted()).
+		Returning(r.columns...)
+
+	for _, f := range r.writableFields() {
+		b.Set(f.column, fieldValue(v, f.copyField))
+	}
+
+	if r.version != nil {
+		b.SetExpr(r.version.column, "clock_timestamp()").
+			Where(postgres.Cond(r.version.column+" = ?", fieldValue(v, r.version.copyField)))
+	}
+
+	query, args, err := b.Build()
+	if err != nil {
+		return zero, err
+	}
+
+	updated, found, err := ScanOneContext[T](ctx, query, args...)
+	if err != nil || found {
+		return updated, err
+	}
+
+	exists, err := r.exists(ctx, id)
+	if err != nil {
+		return zero, err
+	}
+	if exists {
+		return zero, acerrors.NewConflictErrorf("%s %v was modified concurrently", r.table, id)
+	}
+
+	return zero, acerrors.NewNotFoundErrorf("%s %v not found", r.table, id)
+}
+
+// Upsert inserts the row, or updates the row it conflicts with on the conflict columns, which default to the
+// primary key, and returns the row as inserted or updated. The primary key and the generated columns of an existing
+// row are never updated, and the version column is bumped. Since a generated primary key is replaced on every
+// insert, e.g. by generate_primary_key_trigger, rows never conflict on it: it is an error to upsert on the primary
+// key when it is generated, and the conflict columns must then be another unique key.
+func (r *Repository[T, ID]) Upsert(ctx context.Context, row T, conflictColumns ...string) (T, error) {
+	var zero T
+
+	if len(conflictColumns) == 0 {
+		conflictColumns = []string{r.primaryKey.column}
+	}
+
+	if r.primaryKey.generated && slices.Contains(conflictColumns, r.primaryKey.column) {
+		return zero, fmt.Errorf("cannot upsert into %s on its generated primary key, conflict on a unique key instead", r.table)
+	}
+
+	fields := r.writableFields()
+
+	var updates []string
+	for _, f := range fields {
+		if !slices.Contains(conflictColumns, f.column) {
+			updates = append(updates, f.column+" = EXCLUDED."+f.column)
+		}
+	}
+	if r.version != nil {
+		updates = append(updates, r.version.column+" = clock_timestamp()")
+	}
+
+	if !r.primaryKey.generated {
+		fields = append([]repositoryField{r.primaryKey}, fields...)
+	}
+
+	conflict := "ON CONFLICT (" + strings.Join(conflictColumns, ", ") + ") DO NOTHING"
+	if len(updates) > 0 {
+		conflict = "ON CONFLICT (" + strings.Join(conflictColumns, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	query, args, err := postgres.InsertInto(r.table).
+		Named(r.queryName("Upsert")).
+		Columns(columnsOf(fields)...).
+		Values(valuesOf(row, fields)...).
+		Suffix(conflict).
+		Returning(r.columns...).
+		Build()
+	if err != nil {
+		return zero, err
+	}
+
+	upserted, _, err := ScanOneContext[T](ctx, query, args...)
+	return upserted, err
+}
+
+// Delete deletes the row with the ID, soft deleted or not, returning false when there is none.
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) (bool, error) {
+	query, args, err := postgres.DeleteFrom(r.table).
+		Named(r.queryName("Delete")).
+		Where(postgres.Cond(r.primaryKey.column+" = ?", id)).
+		Build()
+	if err != nil {
+		return false, err
+	}
+
+	return ExecDeleteContext(ctx, query, args...)
+}
+
+// SoftDelete marks the row with the ID as deleted, returning false when there is none or it is already deleted.
+// It is an error when the table has no soft delete column.
+func (r *Repository[T, ID]) SoftDelete(ctx context.Context, id ID) (bool, error) {
+	if r.softDelete == nil {
+		return false, fmt.Errorf("%s has no soft delete column", r.table)
+	}
+
+	query, args, err := postgres.Update(r.table).
+		Named(r.queryName("SoftDelete")).
+		SetExpr(r.softDelete.column, "NOW()").
+		Where(postgres.Cond(r.primaryKey.column+" = ?", id), r.notDeleted()).
+		Build()
+	if err != nil {
+		return false, err
+	}
+
+	return ExecUpdateContext(ctx, query, args...)
+}
+
+func (r *Repository[T, ID]) exists(ctx context.Context, id any) (bool, error) {
+	query, args, err := postgres.Select("1").
+		Named(r.queryName("Exists")).
+		From(r.table).
+		Where(postgres.Cond(r.primaryKey.column+" = ?", id), r.notDeleted()).
+		Build()
+	if err != nil {
+		return false, err
+	}
+
+	_, exists, err := ScanOneContext[int](ctx, query, args...)
+	return exists, err
+}
+
+func (r *Repository[T, ID]) selectWhere(operation string, predicates []postgres.Predicate) *postgres.SelectBuilder {
+	return postgres.Select(r.columns...).
+		Named(r.queryName(operation)).
+		From(r.table).
+		Where(predicates...).
+		Where(r.notDeleted())
+}
+
+// notDeleted is the predicate leaving out soft deleted rows, dropped when the table has no soft delete column.
+func (r *Repository[T, ID]) notDeleted() postgres.Predicate {
+	if r.softDelete == nil {
+		return postgres.If(false, postgres.Predicate{})
+	}
+
+	return postgres.Cond(r.softDelete.column + " IS NULL")
+}
+
+// writableFields returns the fields written from the row, which are neither the primary key, nor generated, nor the
+// version column set by the repository.
+func (r *Repository[T, ID]) writableFields() []repositoryField {
+	var fields []repositoryField
+	for _, f := range r.fields {
+		if !f.primaryKey && !f.generated && (r.version == nil || f.column != r.version.column) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// queryName labels the statements of the repository in logs and metrics, e.g. "timelines.Insert".
+func (r *Repository[T, ID]) queryName(operation string) string {
+	return r.table + "." + operation
+}
+
+func columnsOf(fields []repositoryField) []string {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.column
+	}
+
+	return columns
+}
+
+func valuesOf(row any, fields []repositoryField) []any {
+	v := reflect.ValueOf(row)
+
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		values[i] = fieldValue(v, f.copyField)
+	}
+
+	return values
+}
+
+// fieldValue returns the value of the field of the struct, or of the struct pointed to, and nil when it is reached
+// through a nil pointer.
+func fieldValue(v reflect.Value, f copyField) any {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	fv, err := v.FieldByIndexErr(f.index)
+	if err != nil {
+		return nil
+	}
+
+	return fv.Interface()
+}
@@ -0,0 +1,58 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+)
+
+const (
+	labelRLSBypassReason = "rlsBypassReason"
+
+	// setLocalQuery is the parameterized form of SET LOCAL, which cannot take parameters.
+	setLocalQuery = `SELECT set_config(setting.name, setting.value, true) FROM unnest($1::text[], $2::text[]) AS setting(name, value)`
+)
+
+type sessionSetting struct {
+	name    string
+	resolve postgres.SettingResolver
+}
+
+// WithSessionSetting sets the setting called name, e.g. [postgres.SettingUserID], at the start of every transaction
+// begun with a context the resolver returns a value for. The setting is local to the transaction, so it never
+// leaks to other users of the pooled connection.
+func WithSessionSetting(name string, resolve postgres.SettingResolver) Option {
+	return func(db *db) {
+		db.sessionSettings = append(db.sessionSettings, sessionSetting{name: name, resolve: resolve})
+	}
+}
+
+// applySessionSettings sets the resolved session settings locally on a new transaction, along with the bypass of
+// row-level security when the context asks for it.
+func (db *db) applySessionSettings(ctx context.Context, tx pgx.Tx) error {
+	var names, values []string
+
+	for _, setting := range db.sessionSettings {
+		if value, ok := setting.resolve(ctx); ok {
+			names = append(names, setting.name)
+			values = append(values, value)
+		}
+	}
+
+	if reason, ok := postgres.RLSBypassReason(ctx); ok {
+		db.logger.With(labelRLSBypassReason, reason).WarnContext(ctx, "Bypassing row-level security")
+		names = append(names, postgres.SettingBypassRLS)
+		values = append(values, "on")
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, setLocalQuery, names, values); err != nil {
+		return fmt.Errorf("could not apply session settings: %w", postgres.TranslateError(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"time"
+)
+
+const defaultStatsInterval = 15 * time.Second
+
+// startStatsCollection snapshots the pool statistics right away, and then periodically in a background goroutine
+// until the database is shut down. The statistics are also registered with the default prometheus registry, unless
+// an earlier database already registered the same metrics.
+func (db *db) startStatsCollection(ctx context.Context) {
+	db.collectStats()
+
+	if err := prometheus.Register(db.statsCollector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			db.logger.WithError(err).WarnContext(ctx, "Problem registering database stats with prometheus")
+		}
+	} else {
+		db.statsRegistered = true
+	}
+
+	interval := defaultStatsInterval
+	if db.config.StatsInterval > 0 {
+		interval = db.config.StatsInterval
+	}
+
+	db.bgWG.Add(1)
+	go func() {
+		defer db.bgWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-db.stop:
+				return
+			case <-ticker.C:
+				db.collectStats()
+			}
+		}
+	}()
+}
+
+func (db *db) collectStats() {
+	stats := poolStats(db.sqldb)
+	if db.replicas != nil {
+		stats.Replicas = make(map[string]postgres.DBStats, len(db.replicas.replicas))
+		for _, r := range db.replicas.replicas {
+			stats.Replicas[r.host] = poolStats(r.pool)
+		}
+	}
+
+	db.statsMu.Lock()
+	db.currentStats = stats
+	db.statsMu.Unlock()
+}
+
+func poolStats(pool *pgxpool.Pool) postgres.DBStats {
+	stat := pool.Stat()
+
+	return postgres.DBStats{
+		MaxConnections:          int(stat.MaxConns()),
+		CurrentConnections:      int(stat.TotalConns()),
+		AvailableConnections:    int(stat.IdleConns()),
+		AcquiredConnections:     int(stat.AcquiredConns()),
+		ConstructingConnections: int(stat.ConstructingConns()),
+		EmptyAcquiredCount:      int(stat.EmptyAcquireCount()),
+		NewConnectionsTotal:     int(stat.NewConnsCount()),
+		MaxLifetimeDestroyTotal: int(stat.MaxLifetimeDestroyCount()),
+		MaxIdleDestroyTotal:     int(stat.MaxIdleDestroyCount()),
+		AcquireCount:            int(stat.AcquireCount()),
+		AcquireDuration:         stat.AcquireDuration(),
+		CanceledAcquireCount:    int(stat.CanceledAcquireCount()),
+		CollectedAt:             time.Now(),
+	}
+}
@@ -0,0 +1,64 @@
+package pgx
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
+)
+
+func (db *db) Transaction(ctx context.Context, f func(pgx.Tx) error, opts ...postgres.TxOption) error {
+	return db.TransactionContext(ctx, func(ctx context.Context) error {
+		return f(mustGetContextTx(ctx))
+	}, opts...)
+}
+
+func (db *db) TransactionContext(ctx context.Context, f func(context.Context) error, opts ...postgres.TxOption) error {
+	options, err := db.config.TxOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	// Join the transaction already on the context, in a savepoint when nested transactions are enabled.
+	// Retrying is left to whoever owns the outer transaction, since a failed statement aborts the whole transaction.
+	if _, ok := getContextTx(ctx); ok {
+		if !db.nestedTxEnabled(ctx) {
+			return f(ctx)
+		}
+
+		return db.runTransaction(ctx, options.PgxTxOptions(), f)
+	}
+
+	policy := options.Retry
+	policy.Retryable = postgres.IsRetryable
+	policy.OnRetry = func(ctx context.Context, attempt retry.Attempt) {
+		db.logger.WithError(attempt.Err).WithDur(attempt.Backoff).
+			WarnContextf(ctx, "Transaction failed with a retryable error; retry: %d", attempt.Number)
+	}
+
+	return retry.Do(ctx, policy, func(ctx context.Context) error {
+		return db.runTransaction(ctx, options.PgxTxOptions(), f)
+	})
+}
+
+func (db *db) runTransaction(ctx context.Context, options pgx.TxOptions, f func(context.Context) error) (err error) {
+	ctx, _, err = db.doBeginContextTx(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = mustGetContextTx(ctx).Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	// Closing only fails when the commit or rollback does, so the error of the func is returned otherwise.
+	err = f(ctx)
+	if closeErr := db.CloseContextTx(ctx, err); closeErr != nil {
+		return closeErr
+	}
+
+	return err
+}
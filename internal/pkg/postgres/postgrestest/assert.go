@@ -0,0 +1,39 @@
+package postgrestest
+
+import "testing"
+
+// AssertCommitted fails the test unless exactly n transactions were committed, not counting savepoints.
+func (db *DB) AssertCommitted(t testing.TB, n int) {
+	t.Helper()
+
+	if committed := db.Committed(); committed != n {
+		t.Errorf("postgrestest: %d transactions committed, want %d", committed, n)
+	}
+}
+
+// AssertRolledBack fails the test unless exactly n transactions were rolled back, not counting savepoints.
+func (db *DB) AssertRolledBack(t testing.TB, n int) {
+	t.Helper()
+
+	if rolledBack := db.RolledBack(); rolledBack != n {
+		t.Errorf("postgrestest: %d transactions rolled back, want %d", rolledBack, n)
+	}
+}
+
+// AssertNoOpenTx fails the test when a transaction was neither committed nor rolled back.
+func (db *DB) AssertNoOpenTx(t testing.TB) {
+	t.Helper()
+
+	if open := db.Open(); open != 0 {
+		t.Errorf("postgrestest: %d transactions left open", open)
+	}
+}
+
+// AssertRan fails the test unless the query, identified by its name or its SQL, was run exactly n times.
+func (db *DB) AssertRan(t testing.TB, query string, n int) {
+	t.Helper()
+
+	if ran := db.Ran(query); ran != n {
+		t.Errorf("postgrestest: query '%s' ran %d times, want %d", query, ran, n)
+	}
+}
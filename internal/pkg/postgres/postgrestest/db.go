@@ -0,0 +1,397 @@
+package postgrestest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	acpgx "github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
+	"strings"
+	"sync"
+)
+
+// errNoPool is returned by the methods of [DB] that hand out pooled connections, which a fake cannot provide.
+var errNoPool = errors.New("postgrestest: the fake database has no connection pool")
+
+// A Query is a query run on a fake [DB].
+type Query struct {
+	// Name is the name of the query when it was loaded by a [postgres.QueryRegistry] or built by a named builder.
+	Name string
+	SQL  string
+	Args []any
+}
+
+// A DB is an in-memory fake of [postgres.DB] for unit tests of code running queries on the context transaction,
+// such as services and DAOs. Transactions are [Tx] fakes, which record whether they were committed or rolled back,
+// and queries return the results scripted with [DB.On], so no database is needed:
+//
+//	db := postgrestest.NewDB()
+//	db.On("InsertTimeline", postgrestest.Rows([]string{"id"}, []any{"tl_1"}))
+//	db.OnAny(postgrestest.Error(errors.New("boom")))
+//
+//	_, err := timelines.NewService(logger, db, pagination.Config{}).CreateTimeline(ctx, "u_1", req)
+//
+//	db.AssertRan(t, "InsertTimeline", 1)
+//	db.AssertRolledBack(t, 1)
+//
+// Transactions are neither retried nor isolated, and methods handing out pooled connections return an error.
+type DB struct {
+	mu sync.Mutex
+
+	// scripts are the results of the queries, by name or SQL, consumed in order with the last one repeating
+	scripts map[string][]Result
+	// fallback is the result of unscripted queries, which fail when it is nil
+	fallback *Result
+	beginErr error
+	// commitErr makes commits fail, rolling the transaction back
+	commitErr error
+
+	txs         []*Tx
+	queries     []Query
+	subscribers map[string][]chan postgres.Notification
+}
+
+var _ postgres.DB = (*DB)(nil)
+
+// NewDB creates a fake [DB] with no scripted results.
+func NewDB() *DB {
+	return &DB{
+		scripts:     map[string][]Result{},
+		subscribers: map[string][]chan postgres.Notification{},
+	}
+}
+
+// On scripts the result of the query, identified by its name, such as "ListTimelines", or its SQL. Results
+// scripted for the same query are returned in order, with the last one returned for every later run.
+func (db *DB) On(query string, results ...Result) *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := strings.TrimSpace(query)
+	db.scripts[key] = append(db.scripts[key], results...)
+
+	return db
+}
+
+// OnAny scripts the result of the queries without a result of their own. Without it, they fail.
+func (db *DB) OnAny(result Result) *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.fallback = &result
+	return db
+}
+
+// FailBegin makes beginning transactions fail with err, or succeed again when err is nil.
+func (db *DB) FailBegin(err error) *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.beginErr = err
+	return db
+}
+
+// FailCommit makes committing transactions fail with err, or succeed again when err is nil.
+func (db *DB) FailCommit(err error) *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.commitErr = err
+	return db
+}
+
+// Txs returns the transactions and savepoints begun, in order.
+func (db *DB) Txs() []*Tx {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]*Tx(nil), db.txs...)
+}
+
+// Queries returns the queries run, in order.
+func (db *DB) Queries() []Query {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]Query(nil), db.queries...)
+}
+
+// Begun returns the number of transactions begun, not counting savepoints.
+func (db *DB) Begun() int {
+	return db.countTxs(func(*Tx) bool { return true })
+}
+
+// Committed returns the number of transactions committed, not counting savepoints.
+func (db *DB) Committed() int {
+	return db.countTxs(func(tx *Tx) bool { return tx.committed })
+}
+
+// RolledBack returns the number of transactions rolled back, not counting savepoints.
+func (db *DB) RolledBack() int {
+	return db.countTxs(func(tx *Tx) bool { return tx.rolledBack })
+}
+
+// Open returns the number of transactions neither committed nor rolled back, not counting savepoints.
+func (db *DB) Open() int {
+	return db.countTxs(func(tx *Tx) bool { return !tx.committed && !tx.rolledBack })
+}
+
+func (db *DB) countTxs(matches func(*Tx) bool) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := 0
+	for _, tx := range db.txs {
+		if tx.parent == nil && matches(tx) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Ran returns the number of times the query, identified by its name or its SQL, was run.
+func (db *DB) Ran(query string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := strings.TrimSpace(query)
+	n := 0
+	for _, q := range db.queries {
+		if q.Name == key || strings.TrimSpace(q.SQL) == key {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (db *DB) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return nil, errNoPool
+}
+
+func (db *DB) Connect(context.Context) error {
+	return nil
+}
+
+func (db *DB) Run(context.Context, func(*pgxpool.Conn) error) error {
+	return errNoPool
+}
+
+func (db *DB) Transaction(ctx context.Context, f func(pgx.Tx) error, opts ...postgres.TxOption) error {
+	return db.TransactionContext(ctx, func(ctx context.Context) error {
+		tx, _ := ctx.Value(txKey{}).(*Tx)
+		return f(tx)
+	}, opts...)
+}
+
+// TransactionContext runs the func in a fake transaction, or in the one already on the context. It is never
+// retried, and the options are ignored.
+func (db *DB) TransactionContext(ctx context.Context, f func(context.Context) error, _ ...postgres.TxOption) (err error) {
+	ctx, owner, err := db.BeginContextTx(ctx)
+	if err != nil {
+		return err
+	}
+	if !owner {
+		return f(ctx)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = db.CloseContextTx(ctx, fmt.Errorf("panic: %v", p))
+			panic(p)
+		}
+	}()
+
+	err = f(ctx)
+	if closeErr := db.CloseContextTx(ctx, err); closeErr != nil {
+		return closeErr
+	}
+
+	return err
+}
+
+func (db *DB) ContextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	if _, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return ctx
+	}
+
+	fake, ok := tx.(*Tx)
+	if !ok {
+		panic(fmt.Sprintf("postgrestest: %T is not a fake transaction", tx))
+	}
+
+	return db.contextWithTx(ctx, fake)
+}
+
+// BeginContextTx begins a fake transaction, unless the context already has one. Like the real database, it
+// creates a savepoint instead when the context asks for nested transactions.
+func (db *DB) BeginContextTx(ctx context.Context) (context.Context, bool, error) {
+	parent, ok := ctx.Value(txKey{}).(*Tx)
+	if ok && !postgres.IsNestedTxContext(ctx) {
+		return ctx, false, nil
+	}
+
+	tx, err := db.begin(parent)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not begin tx: %w", err)
+	}
+
+	return db.contextWithTx(ctx, tx), true, nil
+}
+
+func (db *DB) BeginContextReadOnlyTx(ctx context.Context) (context.Context, bool, error) {
+	return db.BeginContextTx(ctx)
+}
+
+func (db *DB) CloseContextTx(ctx context.Context, err error) error {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok {
+		panic("no transaction on context")
+	}
+
+	if err == nil {
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			return fmt.Errorf("could not commit tx: %w", commitErr)
+		}
+	} else {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+			return fmt.Errorf("could not rollback tx: %s; original cause: %w", rollbackErr, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) CurrentLSN(context.Context) (postgres.LSN, error) {
+	return 0, nil
+}
+
+func (db *DB) NewBatch(statements []postgres.BatchStatement) *pgx.Batch {
+	b := &pgx.Batch{}
+	for _, s := range statements {
+		b.Queue(s.Query, s.Arguments...)
+	}
+
+	return b
+}
+
+// Subscribe returns the notifications sent with [DB.Notify] by committed transactions, until the context is done.
+func (db *DB) Subscribe(ctx context.Context, channel string) (<-chan postgres.Notification, error) {
+	ch := make(chan postgres.Notification, 64)
+
+	db.mu.Lock()
+	db.subscribers[channel] = append(db.subscribers[channel], ch)
+	db.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		subscribers := db.subscribers[channel]
+		for i, sub := range subscribers {
+			if sub == ch {
+				db.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// Notify sends the payload to the subscribers of the channel when the context transaction commits.
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok {
+		panic("no transaction on context")
+	}
+
+	if err := tx.checkOpen(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	tx.notifications = append(tx.notifications, notification{channel: channel, payload: payload})
+	db.mu.Unlock()
+
+	return nil
+}
+
+func (db *DB) Shutdown(context.Context) error {
+	return nil
+}
+
+func (db *DB) SecurityString() [32]byte {
+	return [32]byte{}
+}
+
+func (db *DB) Stats() postgres.DBStats {
+	return postgres.DBStats{}
+}
+
+// txKey keeps the fake transaction on the context alongside the one the query helpers of the pgx package read, so
+// the fake never mistakes a real transaction for its own.
+type txKey struct{}
+
+func (db *DB) contextWithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(acpgx.ContextWithTx(ctx, tx), txKey{}, tx)
+}
+
+func (db *DB) begin(parent *Tx) (*Tx, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.beginErr != nil {
+		return nil, db.beginErr
+	}
+
+	tx := &Tx{db: db, parent: parent}
+	db.txs = append(db.txs, tx)
+
+	return tx, nil
+}
+
+// result records the query and returns its scripted result.
+func (db *DB) result(sql string, args []any) Result {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	name, _ := postgres.QueryName(sql)
+	db.queries = append(db.queries, Query{Name: name, SQL: sql, Args: args})
+
+	for _, key := range []string{name, strings.TrimSpace(sql)} {
+		results, ok := db.scripts[key]
+		if key == "" || !ok || len(results) == 0 {
+			continue
+		}
+
+		if len(results) > 1 {
+			db.scripts[key] = results[1:]
+		}
+		return results[0]
+	}
+
+	if db.fallback != nil {
+		return *db.fallback
+	}
+
+	return Error(fmt.Errorf("postgrestest: no result scripted for query: %s", sql))
+}
+
+func (db *DB) deliver(n notification) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, ch := range db.subscribers[n.channel] {
+		select {
+		case ch <- postgres.Notification{Channel: n.channel, Payload: n.payload}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package postgrestest
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"reflect"
+	"strconv"
+)
+
+// A Result is the scripted outcome of a query run on a fake [DB]. Create one with [Rows], [Affected] or [Error].
+type Result struct {
+	columns      []string
+	rows         [][]any
+	rowsAffected int64
+	err          error
+}
+
+// Rows is the result of a query returning the rows, each holding a value for every column. The values are scanned
+// into the destinations of the query by assignment or conversion, so scripted values must have types the
+// destinations accept, e.g. time.Time for a time.Time field.
+func Rows(columns []string, rows ...[]any) Result {
+	return Result{columns: columns, rows: rows, rowsAffected: int64(len(rows))}
+}
+
+// Affected is the result of a statement affecting n rows but returning none.
+func Affected(n int64) Result {
+	return Result{rowsAffected: n}
+}
+
+// Error is the result of a query failing with err, e.g. a [pgconn.PgError] with a unique violation code.
+func Error(err error) Result {
+	return Result{err: err}
+}
+
+func (r Result) commandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag("FAKE " + strconv.FormatInt(r.rowsAffected, 10))
+}
+
+// rows is the [pgx.Rows] of a scripted result.
+type rows struct {
+	result  Result
+	current int
+	closed  bool
+	err     error
+}
+
+func newRows(result Result) *rows {
+	return &rows{result: result, current: -1}
+}
+
+func (r *rows) Close() {
+	r.closed = true
+}
+
+func (r *rows) Err() error {
+	return r.err
+}
+
+func (r *rows) CommandTag() pgconn.CommandTag {
+	return r.result.commandTag()
+}
+
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.result.columns))
+	for i, column := range r.result.columns {
+		fields[i] = pgconn.FieldDescription{Name: column}
+	}
+
+	return fields
+}
+
+func (r *rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+
+	r.current++
+	if r.current >= len(r.result.rows) {
+		r.Close()
+		return false
+	}
+
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	values, err := r.Values()
+	if err != nil {
+		return err
+	}
+
+	if len(dest) != len(values) {
+		r.err = fmt.Errorf("postgrestest: scanning %d values into %d destinations", len(values), len(dest))
+		return r.err
+	}
+
+	for i, d := range dest {
+		if err := assign(d, values[i]); err != nil {
+			r.err = fmt.Errorf("postgrestest: scanning column '%s': %w", r.result.columns[i], err)
+			return r.err
+		}
+	}
+
+	return nil
+}
+
+func (r *rows) Values() ([]any, error) {
+	if r.current < 0 || r.current >= len(r.result.rows) {
+		return nil, fmt.Errorf("postgrestest: no current row")
+	}
+
+	values := r.result.rows[r.current]
+	if len(values) != len(r.result.columns) {
+		return nil, fmt.Errorf("postgrestest: row %d has %d values for %d columns", r.current+1, len(values), len(r.result.columns))
+	}
+
+	return values, nil
+}
+
+func (r *rows) RawValues() [][]byte {
+	return nil
+}
+
+func (r *rows) Conn() *pgx.Conn {
+	return nil
+}
+
+// row is the [pgx.Row] of a scripted result.
+type row struct {
+	rows *rows
+	err  error
+}
+
+func (r row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		return pgx.ErrNoRows
+	}
+
+	return r.rows.Scan(dest...)
+}
+
+// assign stores the value in the destination pointer, converting it when needed, e.g. from int to int64. A nil value
+// stores the zero value.
+func assign(dest, value any) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return fmt.Errorf("destination %T is not a non-nil pointer", dest)
+	}
+	target := dv.Elem()
+
+	if value == nil {
+		target.SetZero()
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(target.Type()):
+		target.Set(v)
+	case target.Kind() == reflect.Pointer && v.Type().AssignableTo(target.Type().Elem()):
+		p := reflect.New(target.Type().Elem())
+		p.Elem().Set(v)
+		target.Set(p)
+	case convertible(v.Type(), target.Type()):
+		target.Set(v.Convert(target.Type()))
+	default:
+		return fmt.Errorf("cannot assign %T to %s", value, target.Type())
+	}
+
+	return nil
+}
+
+// convertible returns true when values of type from may be converted to type to without changing their meaning: from a
+// number to another number, or between types of the same kind, e.g. from string to a named string type. Conversions
+// such as int to string, which yields a one-rune string, are not allowed.
+func convertible(from, to reflect.Type) bool {
+	if !from.ConvertibleTo(to) {
+		return false
+	}
+
+	return from.Kind() == to.Kind() || isNumeric(from.Kind()) && isNumeric(to.Kind())
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
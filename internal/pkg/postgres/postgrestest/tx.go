@@ -0,0 +1,222 @@
+package postgrestest
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// A Tx is a fake [pgx.Tx] that runs its queries against the scripted results of its [DB].
+type Tx struct {
+	db     *DB
+	parent *Tx
+
+	committed  bool
+	rolledBack bool
+	// notifications are sent to the subscribers of the DB on commit
+	notifications []notification
+}
+
+type notification struct {
+	channel string
+	payload string
+}
+
+// Committed reports whether the transaction was committed.
+func (tx *Tx) Committed() bool {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	return tx.committed
+}
+
+// RolledBack reports whether the transaction was rolled back.
+func (tx *Tx) RolledBack() bool {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	return tx.rolledBack
+}
+
+// Begin creates a fake savepoint, which is recorded as a transaction of the DB.
+func (tx *Tx) Begin(context.Context) (pgx.Tx, error) {
+	if err := tx.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	return tx.db.begin(tx)
+}
+
+func (tx *Tx) Commit(context.Context) error {
+	tx.db.mu.Lock()
+	if tx.committed || tx.rolledBack {
+		tx.db.mu.Unlock()
+		return pgx.ErrTxClosed
+	}
+
+	if tx.db.commitErr != nil {
+		err := tx.db.commitErr
+		tx.rolledBack = true
+		tx.db.mu.Unlock()
+		return err
+	}
+
+	tx.committed = true
+	notifications := tx.notifications
+	if tx.parent != nil {
+		// A released savepoint hands its notifications to the enclosing transaction.
+		tx.parent.notifications = append(tx.parent.notifications, notifications...)
+		notifications = nil
+	}
+	tx.db.mu.Unlock()
+
+	for _, n := range notifications {
+		tx.db.deliver(n)
+	}
+
+	return nil
+}
+
+func (tx *Tx) Rollback(context.Context) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+
+	tx.rolledBack = true
+	tx.notifications = nil
+
+	return nil
+}
+
+// CopyFrom consumes the rows of the source, recording the query as "COPY <table>", and returns the number of rows.
+func (tx *Tx) CopyFrom(_ context.Context, tableName pgx.Identifier, _ []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if _, err := tx.run("COPY "+tableName.Sanitize(), nil); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for rowSrc.Next() {
+		if _, err := rowSrc.Values(); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rowSrc.Err()
+}
+
+// SendBatch runs the queued queries of the batch one after the other.
+func (tx *Tx) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	return &batchResults{tx: tx, queries: b.QueuedQueries}
+}
+
+func (tx *Tx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (tx *Tx) Prepare(_ context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+func (tx *Tx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	result, err := tx.run(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return result.commandTag(), nil
+}
+
+func (tx *Tx) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	result, err := tx.run(sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRows(result), nil
+}
+
+func (tx *Tx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	result, err := tx.run(sql, args)
+	if err != nil {
+		return row{err: err}
+	}
+
+	return row{rows: newRows(result)}
+}
+
+func (tx *Tx) Conn() *pgx.Conn {
+	return nil
+}
+
+func (tx *Tx) run(sql string, args []any) (Result, error) {
+	if err := tx.checkOpen(); err != nil {
+		return Result{}, err
+	}
+
+	result := tx.db.result(sql, args)
+	return result, result.err
+}
+
+func (tx *Tx) checkOpen() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+
+	return nil
+}
+
+// batchResults runs the queued queries of a batch as their results are read.
+type batchResults struct {
+	tx      *Tx
+	queries []*pgx.QueuedQuery
+	next    int
+}
+
+func (b *batchResults) nextQuery() (*pgx.QueuedQuery, error) {
+	if b.next >= len(b.queries) {
+		return nil, pgx.ErrNoRows
+	}
+
+	q := b.queries[b.next]
+	b.next++
+
+	return q, nil
+}
+
+func (b *batchResults) Exec() (pgconn.CommandTag, error) {
+	q, err := b.nextQuery()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return b.tx.Exec(context.Background(), q.SQL, q.Arguments...)
+}
+
+func (b *batchResults) Query() (pgx.Rows, error) {
+	q, err := b.nextQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.tx.Query(context.Background(), q.SQL, q.Arguments...)
+}
+
+func (b *batchResults) QueryRow() pgx.Row {
+	q, err := b.nextQuery()
+	if err != nil {
+		return row{err: err}
+	}
+
+	return b.tx.QueryRow(context.Background(), q.SQL, q.Arguments...)
+}
+
+func (b *batchResults) Close() error {
+	return nil
+}
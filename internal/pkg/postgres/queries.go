@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// queryNamePrefix starts the comment naming a query, e.g. "-- name: ListTimelines". Named queries keep the comment
+// as their first line, so the name travels with the SQL to the query tracer and to the database logs.
+const queryNamePrefix = "-- name:"
+
+// A Query is a named SQL statement loaded by a [QueryRegistry].
+type Query struct {
+	Name   string
+	SQL    string
+	Source string
+}
+
+// A QueryRegistry holds the named queries loaded from a directory of .sql files. A file holds one or more queries,
+// each starting with a "-- name: <Name>" comment. A file without such a comment holds a single query named after
+// the file, e.g. "list-timelines.sql" holds "ListTimelines".
+type QueryRegistry struct {
+	queries map[string]Query
+}
+
+// LoadQueries loads the queries of the .sql files in dir of fsys. Query names must be unique across the files.
+func LoadQueries(fsys fs.FS, dir string) (*QueryRegistry, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading queries directory '%s': %w", dir, err)
+	}
+
+	r := QueryRegistry{queries: map[string]Query{}}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		source := path.Join(dir, entry.Name())
+		content, err := fs.ReadFile(fsys, source)
+		if err != nil {
+			return nil, fmt.Errorf("reading queries '%s': %w", source, err)
+		}
+
+		queries, err := parseQueries(string(content), queryNameFromFilename(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("parsing queries '%s': %w", source, err)
+		}
+
+		for _, q := range queries {
+			if other, ok := r.queries[q.Name]; ok {
+				return nil, fmt.Errorf("query '%s' is defined in both '%s' and '%s'", q.Name, other.Source, source)
+			}
+			q.Source = source
+			r.queries[q.Name] = q
+		}
+	}
+
+	return &r, nil
+}
+
+// MustLoadQueries is like [LoadQueries], but panics if the queries cannot be loaded. It is meant for loading
+// embedded queries into package variables.
+func MustLoadQueries(fsys fs.FS, dir string) *QueryRegistry {
+	r, err := LoadQueries(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// Get returns the query called name.
+func (r *QueryRegistry) Get(name string) (Query, bool) {
+	q, ok := r.queries[name]
+	return q, ok
+}
+
+// SQL returns the SQL of the query called name, and panics if there is none, since that is a programming error.
+func (r *QueryRegistry) SQL(name string) string {
+	q, ok := r.queries[name]
+	if !ok {
+		panic(fmt.Sprintf("no query named '%s'", name))
+	}
+
+	return q.SQL
+}
+
+// Queries returns every query, ordered by name.
+func (r *QueryRegistry) Queries() []Query {
+	queries := make([]Query, 0, len(r.queries))
+	for _, q := range r.queries {
+		queries = append(queries, q)
+	}
+
+	slices.SortFunc(queries, func(a, b Query) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return queries
+}
+
+// QueryName returns the name of a query loaded by a [QueryRegistry] from its SQL, and false for other SQL.
+func QueryName(sql string) (string, bool) {
+	firstLine, _, _ := strings.Cut(sql, "\n")
+	name, ok := strings.CutPrefix(strings.TrimSpace(firstLine), queryNamePrefix)
+	if !ok {
+		return "", false
+	}
+
+	name = strings.TrimSpace(name)
+	return name, name != ""
+}
+
+func parseQueries(content, defaultName string) ([]Query, error) {
+	var (
+		queries []Query
+		current *Query
+		body    strings.Builder
+	)
+
+	flush := func() error {
+		sql := strings.TrimSpace(body.String())
+		body.Reset()
+
+		if current == nil {
+			if onlyComments(sql) {
+				return nil
+			}
+			current = &Query{Name: defaultName}
+		}
+
+		if onlyComments(sql) {
+			return fmt.Errorf("query '%s' is empty", current.Name)
+		}
+
+		current.SQL = queryNamePrefix + " " + current.Name + "\n" + sql
+		queries = append(queries, *current)
+		current = nil
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := QueryName(line); ok {
+			if current != nil || !onlyComments(body.String()) {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			body.Reset()
+			current = &Query{Name: name}
+			continue
+		}
+
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
+
+// queryNameFromFilename converts a filename like "list-timelines.sql" into a query name like "ListTimelines".
+func queryNameFromFilename(filename string) string {
+	base := strings.TrimSuffix(filename, path.Ext(filename))
+
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(base, func(r rune) bool { return r == '-' || r == '_' || r == '.' || r == ' ' }) {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	return b.String()
+}
+
+// onlyComments reports whether the SQL is empty once its comment lines are removed.
+func onlyComments(sql string) bool {
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+
+	return true
+}
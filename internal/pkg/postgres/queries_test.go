@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseQueries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Query
+	}{
+		{
+			name:    "unnamed query takes the default name",
+			content: "SELECT * FROM timelines;\n",
+			want:    []Query{{Name: "Default", SQL: "-- name: Default\nSELECT * FROM timelines;"}},
+		},
+		{
+			name:    "single named query",
+			content: "-- name: ListTimelines\nSELECT * FROM timelines\nWHERE user_id = $1;\n",
+			want:    []Query{{Name: "ListTimelines", SQL: "-- name: ListTimelines\nSELECT * FROM timelines\nWHERE user_id = $1;"}},
+		},
+		{
+			name: "several named queries",
+			content: `-- name: InsertTimeline
+INSERT INTO timelines (title) VALUES ($1) RETURNING id;
+
+-- name: DeleteTimeline
+DELETE FROM timelines WHERE id = $1;
+`,
+			want: []Query{
+				{Name: "InsertTimeline", SQL: "-- name: InsertTimeline\nINSERT INTO timelines (title) VALUES ($1) RETURNING id;"},
+				{Name: "DeleteTimeline", SQL: "-- name: DeleteTimeline\nDELETE FROM timelines WHERE id = $1;"},
+			},
+		},
+		{
+			name:    "leading comments are dropped",
+			content: "-- queries of the timelines\n\n-- name: CountTimelines\nSELECT COUNT(*) FROM timelines;\n",
+			want:    []Query{{Name: "CountTimelines", SQL: "-- name: CountTimelines\nSELECT COUNT(*) FROM timelines;"}},
+		},
+		{
+			name:    "unnamed query before named ones takes the default name",
+			content: "SELECT 1;\n-- name: Two\nSELECT 2;\n",
+			want: []Query{
+				{Name: "Default", SQL: "-- name: Default\nSELECT 1;"},
+				{Name: "Two", SQL: "-- name: Two\nSELECT 2;"},
+			},
+		},
+		{
+			name:    "comments of a query are kept",
+			content: "-- name: One\n-- the answer\nSELECT 1;\n",
+			want:    []Query{{Name: "One", SQL: "-- name: One\n-- the answer\nSELECT 1;"}},
+		},
+		{
+			name:    "only comments",
+			content: "-- nothing here\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, err := parseQueries(tt.content, "Default")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(queries, tt.want) {
+				t.Errorf("parseQueries() = %+v, want %+v", queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueriesErrors(t *testing.T) {
+	for _, content := range []string{
+		"-- name: Empty\n-- name: Next\nSELECT 1;\n",
+		"-- name: Empty\n-- no SQL\n",
+	} {
+		if _, err := parseQueries(content, "Default"); err == nil {
+			t.Errorf("parseQueries(%q) should fail", content)
+		}
+	}
+}
+
+func TestQueryNameFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"list-timelines.sql":  "ListTimelines",
+		"insert_timeline.sql": "InsertTimeline",
+		"timelines.sql":       "Timelines",
+	}
+
+	for filename, want := range tests {
+		if got := queryNameFromFilename(filename); got != want {
+			t.Errorf("queryNameFromFilename(%q) = %s, want %s", filename, got, want)
+		}
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		sql   string
+		name  string
+		found bool
+	}{
+		{sql: "-- name: ListTimelines\nSELECT 1", name: "ListTimelines", found: true},
+		{sql: "  -- name:  Padded  \nSELECT 1", name: "Padded", found: true},
+		{sql: "-- name:\nSELECT 1"},
+		{sql: "SELECT 1 -- name: NotFirst"},
+		{sql: "-- a comment\n-- name: NotFirst\nSELECT 1"},
+	}
+
+	for _, tt := range tests {
+		name, found := QueryName(tt.sql)
+		if name != tt.name || found != tt.found {
+			t.Errorf("QueryName(%q) = %s, %t, want %s, %t", tt.sql, name, found, tt.name, tt.found)
+		}
+	}
+}
+
+func TestLoadQueries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/timelines.sql":      {Data: []byte("-- name: InsertTimeline\nINSERT INTO timelines (title) VALUES ($1);\n-- name: CountTimelines\nSELECT COUNT(*) FROM timelines;\n")},
+		"sql/list-timelines.sql": {Data: []byte("SELECT * FROM timelines;\n")},
+		"sql/README.md":          {Data: []byte("not queries")},
+	}
+
+	r, err := LoadQueries(fsys, "sql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, q := range r.Queries() {
+		names = append(names, q.Name)
+	}
+	if want := []string{"CountTimelines", "InsertTimeline", "ListTimelines"}; !slices.Equal(names, want) {
+		t.Errorf("loaded queries %q, want %q", names, want)
+	}
+
+	q, ok := r.Get("ListTimelines")
+	if !ok || q.Source != "sql/list-timelines.sql" {
+		t.Errorf("unexpected query: %+v", q)
+	}
+}
+
+func TestLoadQueriesRejectsDuplicateNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/a.sql": {Data: []byte("-- name: Same\nSELECT 1;\n")},
+		"sql/b.sql": {Data: []byte("-- name: Same\nSELECT 2;\n")},
+	}
+
+	if _, err := LoadQueries(fsys, "sql"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ReplicaSelectionRoundRobin spreads read-only transactions over the healthy replicas in turn.
+	ReplicaSelectionRoundRobin = "round_robin"
+	// ReplicaSelectionLeastConnections sends read-only transactions to the healthy replica with the fewest
+	// acquired connections.
+	ReplicaSelectionLeastConnections = "least_connections"
+)
+
+// An LSN is a position in the write-ahead log, which replicas replay to catch up with the primary.
+type LSN uint64
+
+// ParseLSN parses the textual form of an LSN, e.g. "16/B374D848".
+func ParseLSN(raw string) (LSN, error) {
+	high, low, found := strings.Cut(raw, "/")
+	if !found {
+		return 0, fmt.Errorf("'%s' is not a valid LSN", raw)
+	}
+
+	h, err := strconv.ParseUint(high, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid LSN: %w", raw, err)
+	}
+
+	l, err := strconv.ParseUint(low, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid LSN: %w", raw, err)
+	}
+
+	return LSN(h<<32 | l), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(l)>>32, uint32(l))
+}
+
+type (
+	minLSNContextKey        struct{}
+	maxReplicaLagContextKey struct{}
+)
+
+// ContextWithMinLSN makes read-only transactions begun with the returned context use a replica that has replayed
+// at least up to lsn, or the primary when no replica has. Passing the result of [DB.CurrentLSN] after a write
+// gives read-your-writes consistency.
+func ContextWithMinLSN(ctx context.Context, lsn LSN) context.Context {
+	return context.WithValue(ctx, minLSNContextKey{}, lsn)
+}
+
+// MinLSN returns the LSN set with [ContextWithMinLSN], and whether one was set.
+func MinLSN(ctx context.Context) (LSN, bool) {
+	lsn, ok := ctx.Value(minLSNContextKey{}).(LSN)
+	return lsn, ok
+}
+
+// ContextWithMaxReplicaLag makes read-only transactions begun with the returned context use a replica lagging
+// behind the primary by at most maxLag, or the primary when no replica is that fresh.
+func ContextWithMaxReplicaLag(ctx context.Context, maxLag time.Duration) context.Context {
+	return context.WithValue(ctx, maxReplicaLagContextKey{}, maxLag)
+}
+
+// MaxReplicaLag returns the lag set with [ContextWithMaxReplicaLag], and whether one was set.
+func MaxReplicaLag(ctx context.Context) (time.Duration, bool) {
+	maxLag, ok := ctx.Value(maxReplicaLagContextKey{}).(time.Duration)
+	return maxLag, ok
+}
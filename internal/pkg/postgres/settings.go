@@ -0,0 +1,27 @@
+package postgres
+
+import "context"
+
+const (
+	// SettingUserID is the setting row-level security policies compare the owner of a row to.
+	SettingUserID = "app.user_id"
+	// SettingBypassRLS is the setting that makes row-level security policies let every row through, when "on".
+	SettingBypassRLS = "app.bypass_rls"
+)
+
+// A SettingResolver returns the value of a setting from the context, and false when the context has none.
+type SettingResolver func(context.Context) (string, bool)
+
+type rlsBypassContextKey struct{}
+
+// ContextWithRLSBypass makes transactions begun with the returned context bypass row-level security, for jobs that
+// work across tenants. The reason is logged whenever such a transaction begins, so every bypass can be audited.
+func ContextWithRLSBypass(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, rlsBypassContextKey{}, reason)
+}
+
+// RLSBypassReason returns the reason given to [ContextWithRLSBypass], and whether row-level security is bypassed.
+func RLSBypassReason(ctx context.Context) (string, bool) {
+	reason, ok := ctx.Value(rlsBypassContextKey{}).(string)
+	return reason, ok
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	achttp "github.com/zhughes3/go-accelerate/internal/pkg/http"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"net/http"
+)
+
+const (
+	defaultSubsystem = "database"
+	// poolPrimary labels the stats of the pool of the primary
+	poolPrimary = "primary"
+)
+
+// StatsCollector is a [prometheus.Collector] that exports the most recently collected [DBStats], labeled by pool:
+// "primary", or the host of a read replica.
+type StatsCollector struct {
+	stats func() DBStats
+
+	maxConnections          *prometheus.Desc
+	currentConnections      *prometheus.Desc
+	availableConnections    *prometheus.Desc
+	acquiredConnections     *prometheus.Desc
+	constructingConnections *prometheus.Desc
+	emptyAcquired           *prometheus.Desc
+	newConnections          *prometheus.Desc
+	maxLifetimeDestroyed    *prometheus.Desc
+	maxIdleDestroyed        *prometheus.Desc
+	acquires                *prometheus.Desc
+	acquireDuration         *prometheus.Desc
+	canceledAcquires        *prometheus.Desc
+}
+
+// NewStatsCollector creates a [StatsCollector] reporting the result of the stats func under the given metrics
+// subsystem, which defaults to "database" when blank.
+func NewStatsCollector(stats func() DBStats, subsystem string) *StatsCollector {
+	if acstrings.IsBlank(subsystem) {
+		subsystem = defaultSubsystem
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("", subsystem, name), help, []string{"pool"}, nil)
+	}
+
+	return &StatsCollector{
+		stats:                   stats,
+		maxConnections:          desc("connections_max", "Maximum size of the connection pool."),
+		currentConnections:      desc("connections_current", "Current number of connections in the pool."),
+		availableConnections:    desc("connections_idle", "Number of idle connections in the pool."),
+		acquiredConnections:     desc("connections_acquired", "Number of connections currently acquired from the pool."),
+		constructingConnections: desc("connections_constructing", "Number of connections being established."),
+		emptyAcquired:           desc("acquire_empty_total", "Number of acquires that waited for a connection because the pool was empty."),
+		newConnections:          desc("connections_new_total", "Number of new connections opened."),
+		maxLifetimeDestroyed:    desc("connections_max_lifetime_destroyed_total", "Number of connections closed for exceeding their maximum lifetime."),
+		maxIdleDestroyed:        desc("connections_max_idle_destroyed_total", "Number of connections closed for exceeding their maximum idle time."),
+		acquires:                desc("acquire_total", "Number of successful connection acquires."),
+		acquireDuration:         desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		canceledAcquires:        desc("acquire_canceled_total", "Number of connection acquires canceled by their context."),
+	}
+}
+
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+
+	c.collectPool(ch, poolPrimary, s)
+	for host, rs := range s.Replicas {
+		c.collectPool(ch, host, rs)
+	}
+}
+
+func (c *StatsCollector) collectPool(ch chan<- prometheus.Metric, pool string, s DBStats) {
+	gauge := func(desc *prometheus.Desc, v int) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), pool)
+	}
+	counter := func(desc *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, pool)
+	}
+
+	gauge(c.maxConnections, s.MaxConnections)
+	gauge(c.currentConnections, s.CurrentConnections)
+	gauge(c.availableConnections, s.AvailableConnections)
+	gauge(c.acquiredConnections, s.AcquiredConnections)
+	gauge(c.constructingConnections, s.ConstructingConnections)
+	counter(c.emptyAcquired, float64(s.EmptyAcquiredCount))
+	counter(c.newConnections, float64(s.NewConnectionsTotal))
+	counter(c.maxLifetimeDestroyed, float64(s.MaxLifetimeDestroyTotal))
+	counter(c.maxIdleDestroyed, float64(s.MaxIdleDestroyTotal))
+	counter(c.acquires, float64(s.AcquireCount))
+	counter(c.acquireDuration, s.AcquireDuration.Seconds())
+	counter(c.canceledAcquires, float64(s.CanceledAcquireCount))
+}
+
+// NewStatsHandler returns an [http.Handler] that responds with the most recently collected [DBStats] of the db.
+func NewStatsHandler(db DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = achttp.EncodeJSONResponse(r.Context(), w, db.Stats())
+	})
+}
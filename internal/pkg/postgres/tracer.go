@@ -0,0 +1,403 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSlowQueryThreshold = 500 * time.Millisecond
+
+	labelSQL          = "sql"
+	labelQuery        = "query"
+	labelArgs         = "args"
+	labelRowsAffected = "rowsAffected"
+	labelCaller       = "caller"
+	labelPlan         = "plan"
+	labelBatchSize    = "batchSize"
+
+	redacted = "<redacted>"
+
+	// unnamedQuery is the metrics label of queries not loaded by a [QueryRegistry], which are counted together to
+	// keep the number of series bounded.
+	unnamedQuery = "unnamed"
+	outcomeOK    = "ok"
+	outcomeError = "error"
+)
+
+// callerSkipPrefixes are the function prefixes of frames skipped when looking for the code that ran a query.
+var callerSkipPrefixes = []string{
+	"runtime.",
+	"github.com/jackc/",
+	"github.com/georgysavva/scany/",
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres",
+}
+
+type (
+	queryTraceKey   struct{}
+	batchTraceKey   struct{}
+	explainTraceKey struct{}
+)
+
+type queryTrace struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+type batchTrace struct {
+	size  int
+	start time.Time
+}
+
+// A queryTracer is a [pgx.QueryTracer] and [pgx.BatchTracer] that records the duration of every query by name.
+// When logging is enabled, it also logs every query with its duration, rows affected and redacted arguments at
+// debug level, and queries slower than the threshold at warn level along with the code that ran them. Queries
+// loaded by a [QueryRegistry] are logged by name rather than by SQL.
+type queryTracer struct {
+	logger    slog.Logger
+	logging   bool
+	threshold time.Duration
+	explain   bool
+	durations *prometheus.HistogramVec
+}
+
+func newQueryTracer(logger slog.Logger, c Config) *queryTracer {
+	threshold := defaultSlowQueryThreshold
+	if c.SlowQueryThreshold > 0 {
+		threshold = c.SlowQueryThreshold
+	}
+
+	return &queryTracer{
+		logger:    logger,
+		logging:   c.EnableDBLogging,
+		threshold: threshold,
+		explain:   c.ExplainSlowQueries,
+		durations: registerQueryDurations(logger, c.Subsystem),
+	}
+}
+
+// registerQueryDurations registers the query duration histogram with the default prometheus registry, reusing the
+// one registered by an earlier connection configuration.
+func registerQueryDurations(logger slog.Logger, subsystem string) *prometheus.HistogramVec {
+	if acstrings.IsBlank(subsystem) {
+		subsystem = defaultSubsystem
+	}
+
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: subsystem,
+		Name:      "query_duration_seconds",
+		Help:      "Duration of queries by name, for the queries loaded from a query registry.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"query", "outcome"})
+
+	if err := prometheus.Register(durations); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(*prometheus.HistogramVec); ok {
+				return existing
+			}
+		}
+		logger.WithError(err).WarnContext(context.Background(), "Problem registering query durations with prometheus")
+	}
+
+	return durations
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if isExplaining(ctx) {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if isExplaining(ctx) {
+		return
+	}
+
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	dur := time.Since(trace.start)
+	t.observe(trace.sql, dur, data.Err)
+
+	if !t.logging {
+		return
+	}
+
+	logger := withQuery(t.logger.WithDur(dur), trace.sql).
+		With(labelArgs, redactArgs(trace.args)).
+		With(labelRowsAffected, data.CommandTag.RowsAffected())
+
+	if data.Err != nil {
+		logger = logger.WithError(data.Err)
+	}
+
+	if dur < t.threshold {
+		logger.DebugContext(ctx, "Executed query")
+		return
+	}
+
+	logger = logger.With(labelCaller, determineCaller())
+
+	if t.explain && data.Err == nil && isExplainable(trace.sql) {
+		plan, err := explainQuery(ctx, conn, trace.sql, trace.args)
+		if err != nil {
+			t.logger.WithError(err).WarnContext(ctx, "Problem explaining slow query")
+		} else {
+			logger = logger.With(labelPlan, plan)
+		}
+	}
+
+	logger.WarnContextf(ctx, "Slow query exceeded %s", t.threshold)
+}
+
+func (t *queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	size := 0
+	if data.Batch != nil {
+		size = data.Batch.Len()
+	}
+
+	return context.WithValue(ctx, batchTraceKey{}, &batchTrace{size: size, start: time.Now()})
+}
+
+func (t *queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if !t.logging {
+		return
+	}
+
+	logger := withQuery(t.logger, data.SQL).
+		With(labelArgs, redactArgs(data.Args)).
+		With(labelRowsAffected, data.CommandTag.RowsAffected())
+
+	if data.Err != nil {
+		logger = logger.WithError(data.Err)
+	}
+
+	logger.DebugContext(ctx, "Executed batched query")
+}
+
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	trace, ok := ctx.Value(batchTraceKey{}).(*batchTrace)
+	if !ok || !t.logging {
+		return
+	}
+
+	dur := time.Since(trace.start)
+	logger := t.logger.WithDur(dur).With(labelBatchSize, trace.size)
+
+	if data.Err != nil {
+		logger = logger.WithError(data.Err)
+	}
+
+	if dur < t.threshold {
+		logger.DebugContext(ctx, "Executed batch")
+		return
+	}
+
+	logger.With(labelCaller, determineCaller()).WarnContextf(ctx, "Slow batch exceeded %s", t.threshold)
+}
+
+func (t *queryTracer) observe(sql string, dur time.Duration, err error) {
+	name, ok := QueryName(sql)
+	if !ok {
+		name = unnamedQuery
+	}
+
+	outcome := outcomeOK
+	if err != nil {
+		outcome = outcomeError
+	}
+
+	t.durations.WithLabelValues(name, outcome).Observe(dur.Seconds())
+}
+
+// withQuery labels the logger with the name of a query loaded by a [QueryRegistry], or with the SQL of any other.
+func withQuery(logger slog.Logger, sql string) slog.Logger {
+	if name, ok := QueryName(sql); ok {
+		return logger.With(labelQuery, name)
+	}
+
+	return logger.With(labelSQL, sql)
+}
+
+func isExplaining(ctx context.Context) bool {
+	explaining, _ := ctx.Value(explainTraceKey{}).(bool)
+	return explaining
+}
+
+// explainQuery captures the JSON plan of sql. Inside a transaction, the plan is captured in a savepoint that is
+// rolled back, so neither the EXPLAIN failing nor what it ran can affect the transaction. See [isAnalyzable] for the
+// statements that are run again with EXPLAIN ANALYZE.
+func explainQuery(ctx context.Context, conn *pgx.Conn, sql string, args []any) (string, error) {
+	if conn == nil {
+		return "", fmt.Errorf("no connection to explain the query on")
+	}
+
+	explain := "EXPLAIN (FORMAT JSON) "
+	if isAnalyzable(sql) {
+		explain = "EXPLAIN (ANALYZE, FORMAT JSON) "
+	}
+
+	ctx = context.WithValue(ctx, explainTraceKey{}, true)
+
+	switch conn.PgConn().TxStatus() {
+	case txStatusIdle:
+		return queryPlan(ctx, conn, explain+sql, args)
+	case txStatusInTransaction:
+		if _, err := conn.Exec(ctx, "SAVEPOINT explain_slow_query"); err != nil {
+			return "", err
+		}
+
+		plan, err := queryPlan(ctx, conn, explain+sql, args)
+		rollback := "ROLLBACK TO SAVEPOINT explain_slow_query; RELEASE SAVEPOINT explain_slow_query"
+		if _, rollbackErr := conn.Exec(ctx, rollback); rollbackErr != nil {
+			return "", fmt.Errorf("could not roll back the explain savepoint: %w", rollbackErr)
+		}
+
+		return plan, err
+	default:
+		return "", fmt.Errorf("the transaction of the query has failed")
+	}
+}
+
+// Transaction statuses of [pgconn.PgConn.TxStatus].
+const (
+	txStatusIdle          = 'I'
+	txStatusInTransaction = 'T'
+)
+
+func queryPlan(ctx context.Context, conn *pgx.Conn, explain string, args []any) (string, error) {
+	var plan string
+	if err := conn.QueryRow(ctx, explain, args...).Scan(&plan); err != nil {
+		return "", err
+	}
+
+	return plan, nil
+}
+
+// explainableStatements are the statements EXPLAIN accepts. Any other statement, such as SET or SAVEPOINT, fails.
+var explainableStatements = []string{"SELECT", "WITH", "INSERT", "UPDATE", "DELETE", "MERGE", "VALUES", "TABLE"}
+
+func isExplainable(sql string) bool {
+	upper := strings.ToUpper(trimLeadingComments(sql))
+	for _, statement := range explainableStatements {
+		if strings.HasPrefix(upper, statement) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	// lockingClausePattern matches the locking clauses of a SELECT, e.g. FOR UPDATE SKIP LOCKED.
+	lockingClausePattern = regexp.MustCompile(`\bFOR\s+(NO\s+KEY\s+UPDATE|UPDATE|KEY\s+SHARE|SHARE)\b`)
+	// callPattern matches a name followed by a parenthesis, i.e. a function call or one of the nonCallKeywords.
+	callPattern = regexp.MustCompile(`([A-Z_][A-Z0-9_$.]*)\s*\(`)
+	// nonCallKeywords are followed by a parenthesis without calling a function.
+	nonCallKeywords = []string{"SELECT", "FROM", "JOIN", "ON", "USING", "WHERE", "AND", "OR", "NOT", "IN", "EXISTS",
+		"ANY", "ALL", "SOME", "AS", "VALUES", "LATERAL", "UNION", "INTERSECT", "EXCEPT", "OVER", "FILTER", "ROW",
+		"CASE", "WHEN", "THEN", "ELSE", "BY", "IS", "LIKE", "ILIKE", "BETWEEN", "WITH"}
+)
+
+// isAnalyzable returns true when the statement can be run again by EXPLAIN ANALYZE without side effects: a SELECT, or
+// a WITH query that does not modify data, that neither takes row locks nor calls functions, since functions such as
+// nextval or pg_advisory_lock have side effects.
+func isAnalyzable(sql string) bool {
+	upper := strings.ToUpper(trimLeadingComments(sql))
+
+	if !strings.HasPrefix(upper, "SELECT") && !strings.HasPrefix(upper, "WITH") {
+		return false
+	}
+
+	// A WITH query may contain data modifying statements.
+	if strings.Contains(upper, "INSERT") || strings.Contains(upper, "UPDATE") || strings.Contains(upper, "DELETE") ||
+		strings.Contains(upper, "MERGE") {
+		return false
+	}
+
+	if lockingClausePattern.MatchString(upper) {
+		return false
+	}
+
+	for _, match := range callPattern.FindAllStringSubmatch(upper, -1) {
+		if !slices.Contains(nonCallKeywords, match[1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trimLeadingComments removes the comment lines before the statement, such as the name of a registry query.
+func trimLeadingComments(sql string) string {
+	sql = strings.TrimSpace(sql)
+	for strings.HasPrefix(sql, "--") {
+		_, rest, _ := strings.Cut(sql, "\n")
+		sql = strings.TrimSpace(rest)
+	}
+
+	return sql
+}
+
+// redactArgs replaces the values of query arguments that may hold personal or secret data, such as strings and
+// byte slices, with their type. Numbers, booleans, times and nulls are kept since they help debugging.
+func redactArgs(args []any) []any {
+	redactedArgs := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64,
+			time.Time, time.Duration:
+			redactedArgs[i] = v
+		default:
+			redactedArgs[i] = fmt.Sprintf("%s(%T)", redacted, arg)
+		}
+	}
+
+	return redactedArgs
+}
+
+// determineCaller returns the file, line and function of the first frame outside of the database packages.
+func determineCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !skipCallerFrame(frame.Function) {
+			file := frame.File[strings.LastIndex(frame.File, "/")+1:]
+			function := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+			return fmt.Sprintf("%s:%d %s", file, frame.Line, function)
+		}
+
+		if !more {
+			return "N/A"
+		}
+	}
+}
+
+func skipCallerFrame(function string) bool {
+	for _, prefix := range callerSkipPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
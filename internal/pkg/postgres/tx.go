@@ -10,6 +10,21 @@ type ResultFunc[T any] func(ctx context.Context) (T, error)
 // returns a value, a found indicator, and an error to be wrapped in a tx.
 type ResultAndFoundFunc[T any] func(ctx context.Context) (T, bool, error)
 
+type nestedTxContextKey struct{}
+
+// ContextWithNestedTx opts in to nested transactions for the returned context. When a transaction is already on
+// the context, [DB.BeginContextTx] creates a SAVEPOINT instead of joining the outer transaction, and
+// [DB.CloseContextTx] releases or rolls back to that savepoint, leaving the outer transaction usable.
+func ContextWithNestedTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nestedTxContextKey{}, true)
+}
+
+// IsNestedTxContext returns true if nested transactions were opted in to with [ContextWithNestedTx].
+func IsNestedTxContext(ctx context.Context) bool {
+	nested, _ := ctx.Value(nestedTxContextKey{}).(bool)
+	return nested
+}
+
 type beginTxFunc func(ctx context.Context) (context.Context, bool, error)
 
 func ExecuteResultFuncInTx[T any](ctx context.Context, db DB, op ResultFunc[T]) (T, error) {
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/zhughes3/go-accelerate/pkg/retry"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTxMaxAttempts         = 3
+	defaultTxRetryInitialBackoff = 50 * time.Millisecond
+	defaultTxRetryMaxBackoff     = 2 * time.Second
+)
+
+// TxOptions configure a transaction started by [DB.Transaction] or [DB.TransactionContext].
+type TxOptions struct {
+	IsoLevel   pgx.TxIsoLevel
+	AccessMode pgx.TxAccessMode
+	// Retry controls how the transaction is run again after failing with an error that is [IsRetryable], i.e. a
+	// serialization failure or a deadlock. Its Retryable and OnRetry funcs are ignored.
+	Retry retry.Policy
+}
+
+func (o TxOptions) PgxTxOptions() pgx.TxOptions {
+	return pgx.TxOptions{
+		IsoLevel:   o.IsoLevel,
+		AccessMode: o.AccessMode,
+	}
+}
+
+// A TxOption overrides one of the default [TxOptions].
+type TxOption func(*TxOptions)
+
+func WithIsolationLevel(level pgx.TxIsoLevel) TxOption {
+	return func(o *TxOptions) {
+		o.IsoLevel = level
+	}
+}
+
+func WithReadOnly() TxOption {
+	return func(o *TxOptions) {
+		o.AccessMode = pgx.ReadOnly
+	}
+}
+
+// WithRetryPolicy sets how the transaction is retried. Note that the attempts of a [retry.Policy] are not limited
+// when its MaxAttempts is not set.
+func WithRetryPolicy(policy retry.Policy) TxOption {
+	return func(o *TxOptions) {
+		o.Retry = policy
+	}
+}
+
+// WithoutRetries runs the transaction exactly once.
+func WithoutRetries() TxOption {
+	return func(o *TxOptions) {
+		o.Retry.MaxAttempts = 1
+	}
+}
+
+// TxOptions returns the default transaction options from the configuration, with the given opts applied.
+func (c Config) TxOptions(opts ...TxOption) (TxOptions, error) {
+	isoLevel, err := parseIsoLevel(c.TxIsolationLevel)
+	if err != nil {
+		return TxOptions{}, err
+	}
+
+	o := TxOptions{
+		IsoLevel: isoLevel,
+		Retry: retry.Policy{
+			MaxAttempts:    defaultTxMaxAttempts,
+			InitialBackoff: defaultTxRetryInitialBackoff,
+			MaxBackoff:     defaultTxRetryMaxBackoff,
+			Jitter:         retryJitter,
+		},
+	}
+
+	if c.TxMaxAttempts > 0 {
+		o.Retry.MaxAttempts = c.TxMaxAttempts
+	}
+
+	if c.TxRetryInitialBackoff > 0 {
+		o.Retry.InitialBackoff = c.TxRetryInitialBackoff
+	}
+
+	if c.TxRetryMaxBackoff > 0 {
+		o.Retry.MaxBackoff = c.TxRetryMaxBackoff
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o, nil
+}
+
+var isoLevels = []pgx.TxIsoLevel{pgx.Serializable, pgx.RepeatableRead, pgx.ReadCommitted, pgx.ReadUncommitted}
+
+// parseIsoLevel parses an isolation level like "serializable" or "read_committed". A blank level uses
+// the database default.
+func parseIsoLevel(raw string) (pgx.TxIsoLevel, error) {
+	if acstrings.IsBlank(raw) {
+		return "", nil
+	}
+
+	normalized := strings.ToLower(strings.NewReplacer("_", " ", "-", " ").Replace(strings.TrimSpace(raw)))
+	for _, level := range isoLevels {
+		if string(level) == normalized {
+			return level, nil
+		}
+	}
+
+	return "", fmt.Errorf("'%s' is not a valid transaction isolation level", raw)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"github.com/zhughes3/go-accelerate/internal/pkg/endpoint"
 	achttp "github.com/zhughes3/go-accelerate/internal/pkg/http"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
 	"github.com/zhughes3/go-accelerate/internal/pkg/v1/api"
 	"github.com/zhughes3/go-accelerate/internal/pkg/v1/user"
+	pkgapi "github.com/zhughes3/go-accelerate/pkg/api"
 	"github.com/zhughes3/go-accelerate/pkg/timelines"
 	"github.com/zhughes3/go-accelerate/pkg/url"
 	"net/http"
@@ -18,6 +20,8 @@ const (
 	pathTimelineList = "timelines"
 )
 
+const tagTimelines = "timelines"
+
 func NewHandlerSpecs(userID user.IDResolver, timelinesService timelines.Service) []achttp.RequestHandlerSpec {
 	return NewTimelineHandlerSpecs(pathV1, timelinesService, userID)
 }
@@ -30,38 +34,47 @@ func NewTimelineHandlerSpecs(pathPrefix string, s timelines.Service, userID user
 }
 
 func newListTimelinesHandlerSpec(pathPrefix string, s timelines.Service, userID user.IDResolver) achttp.RequestHandlerSpec {
-	return achttp.NewRequestHandlerSpec(
+	return achttp.NewTypedRequestHandlerSpec(
 		"list-timelines",
 		DecodeListRequest,
 		NewListTimelinesEndpoint(s, userID),
-		achttp.EncodeJSONResponse,
+		achttp.EncodeTypedResponse,
 		url.CreateFullPath(pathPrefix, pathTimelineList),
 		[]string{http.MethodGet},
+		achttp.WithSummary("List timelines", "Lists the timelines owned by the current user."),
+		achttp.WithTags(tagTimelines),
+		achttp.WithRequestParams[listRequest](),
+		achttp.WithErrorResponse(http.StatusBadRequest, "Invalid request parameters"),
 	)
 }
 
 func newCreateTimelineHandlerSpec(pathPrefix string, s timelines.Service, userID user.IDResolver) achttp.RequestHandlerSpec {
-	return achttp.NewRequestHandlerSpec(
+	return achttp.NewTypedRequestHandlerSpec(
 		"create-timeline",
 		DecodeCreateRequest[api.IdentifiableTimeline],
 		NewCreateTimelineEndpoint(s, userID),
-		achttp.EncodeJSONCreatedResponse,
+		achttp.EncodeTypedCreatedResponse,
 		url.CreateFullPath(pathPrefix, pathTimelineList),
 		[]string{http.MethodPost},
+		achttp.WithMediaTypes(achttp.MediaTypeJSON, achttp.MediaTypeMessagePack, achttp.MediaTypeCBOR, achttp.MediaTypeXML),
+		achttp.WithSummary("Create a timeline", "Creates a new timeline owned by the current user."),
+		achttp.WithTags(tagTimelines),
+		achttp.WithRequestBody[api.IdentifiableTimeline](),
+		achttp.WithResponseBody[timelines.IdentifiableTimeline](http.StatusCreated),
+		achttp.WithErrorResponse(http.StatusBadRequest, "Invalid timeline"),
+		achttp.WithErrorResponse(http.StatusConflict, "Timeline already exists"),
 	)
 }
 
-func NewListTimelinesEndpoint(s timelines.Service, userID user.IDResolver) endpoint.Endpoint {
-	return func(ctx context.Context, _ any) (any, error) {
-		return s.ListTimelines(ctx, userID(ctx))
+func NewListTimelinesEndpoint(s timelines.Service, userID user.IDResolver) endpoint.TypedEndpoint[listRequest, pkgapi.ListResponse[timelines.IdentifiableTimeline]] {
+	return func(ctx context.Context, r listRequest) (pkgapi.ListResponse[timelines.IdentifiableTimeline], error) {
+		return s.ListTimelines(ctx, userID(ctx), timelines.TimelineListReq{NamePrefix: r.NamePrefix, Page: r.Request})
 	}
 }
 
-func NewCreateTimelineEndpoint(s timelines.Service, userID user.IDResolver) endpoint.Endpoint {
-	return func(ctx context.Context, r any) (any, error) {
-		tr := r.(createRequest[api.Timeline])
-
-		return s.CreateTimeline(ctx, userID(ctx), timelines.TimelineCreateReq{Name: tr.entity.Name})
+func NewCreateTimelineEndpoint(s timelines.Service, userID user.IDResolver) endpoint.TypedEndpoint[createRequest[api.IdentifiableTimeline], timelines.IdentifiableTimeline] {
+	return func(ctx context.Context, r createRequest[api.IdentifiableTimeline]) (timelines.IdentifiableTimeline, error) {
+		return s.CreateTimeline(ctx, userID(ctx), timelines.TimelineCreateReq{Name: r.entity.Name})
 	}
 }
 
@@ -69,15 +82,15 @@ type byIDRequest struct {
 	id string
 }
 
-func NewDecodeByIDRequest(paramName string) achttp.DecodeRequestFunc {
-	return func(_ context.Context, r *http.Request) (any, error) {
+func NewDecodeByIDRequest(paramName string) achttp.TypedDecodeRequestFunc[byIDRequest] {
+	return func(_ context.Context, r *http.Request) (byIDRequest, error) {
 		var (
 			request byIDRequest
 			err     error
 		)
 
 		if request.id, err = achttp.RequiredParamValue(r, paramName); err != nil {
-			return nil, err
+			return byIDRequest{}, err
 		}
 
 		return request, nil
@@ -88,21 +101,25 @@ type createRequest[T any] struct {
 	entity T
 }
 
-func DecodeCreateRequest[T any](_ context.Context, r *http.Request) (any, error) {
+func DecodeCreateRequest[T any](_ context.Context, r *http.Request) (createRequest[T], error) {
 	var request createRequest[T]
-	if err := achttp.DecodeJSONRequestBody(r, &request.entity); err != nil {
-		return nil, err
+	if err := achttp.DecodeRequestBody(r, &request.entity); err != nil {
+		return createRequest[T]{}, err
 	}
 
 	return request, nil
 }
 
 type listRequest struct {
-	limit   int
-	page    int
-	orderBy string
+	pagination.Request
+	NamePrefix string `query:"name_prefix"`
 }
 
-func DecodeListRequest(_ context.Context, _ *http.Request) (any, error) {
-	return listRequest{}, nil
+func DecodeListRequest(_ context.Context, r *http.Request) (listRequest, error) {
+	var request listRequest
+	if err := achttp.BindRequestParams(r, &request); err != nil {
+		return listRequest{}, err
+	}
+
+	return request, nil
 }
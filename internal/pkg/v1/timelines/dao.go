@@ -2,35 +2,42 @@ package timelines
 
 import (
 	"context"
-	_ "embed"
+	"embed"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
 	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pgx"
 	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
 	"github.com/zhughes3/go-accelerate/pkg/slog"
+	acstrings "github.com/zhughes3/go-accelerate/pkg/strings"
 )
 
 type dao struct {
-	logger slog.Logger
+	logger    slog.Logger
+	timelines *pgx.Repository[Timeline, string]
 }
 
 type DAO interface {
 	CreateTimeline(ctx context.Context, userID string, req TimelineCreateRequest) (string, error)
-	ListTimelines(ctx context.Context, userID string) ([]Timeline, error)
+	ListTimelines(ctx context.Context, userID string, filter TimelineFilter, page pagination.Page) ([]Timeline, error)
 }
 
 func NewDAO(logger slog.Logger) DAO {
-	return dao{logger}
+	return dao{
+		logger:    logger,
+		timelines: pgx.MustNewRepository[Timeline, string]("timelines"),
+	}
 }
 
-var (
-	//go:embed sql/insert-timeline.sql
-	insertTimeline string
+const queryInsertTimeline = "InsertTimeline"
 
-	//go:embed sql/list-timelines.sql
-	listTimelines string
-)
+//go:embed sql/*.sql
+var queryFiles embed.FS
+
+// Queries holds the queries of the DAO, so they can be validated against the database at startup.
+var Queries = postgres.MustLoadQueries(queryFiles, "sql")
 
 func (d dao) CreateTimeline(ctx context.Context, userID string, req TimelineCreateRequest) (string, error) {
-	timelineID, err := pgx.ExecInsertContextForPrimaryKey(ctx, insertTimeline, req.Name, userID, req.CreatedAt, req.CreatedAt)
+	timelineID, err := pgx.ExecInsertContextForPrimaryKey(ctx, Queries.SQL(queryInsertTimeline), req.Name, userID, req.CreatedAt, req.CreatedAt)
 	if err != nil {
 		return "", acerrors.Wrap(err, "problem inserting timeline")
 	}
@@ -38,6 +45,8 @@ func (d dao) CreateTimeline(ctx context.Context, userID string, req TimelineCrea
 	return timelineID, nil
 }
 
-func (d dao) ListTimelines(ctx context.Context, userID string) ([]Timeline, error) {
-	return pgx.ScanAllContext[Timeline](ctx, listTimelines, userID)
+func (d dao) ListTimelines(ctx context.Context, userID string, filter TimelineFilter, page pagination.Page) ([]Timeline, error) {
+	return d.timelines.ListPage(ctx, page,
+		postgres.Cond("user_id = ?", userID),
+		postgres.If(acstrings.IsNotBlank(filter.NamePrefix), postgres.Cond("starts_with(title, ?)", filter.NamePrefix)))
 }
@@ -1 +0,0 @@
-SELECT id, title, created_at, updated_at FROM timelines WHERE user_id = $1;
\ No newline at end of file
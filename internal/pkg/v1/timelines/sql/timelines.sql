@@ -1 +1,2 @@
-INSERT INTO timelines (title, user_id, created_at, updated_at) VALUES($1, $2, $3, $4);
\ No newline at end of file
+-- name: InsertTimeline
+INSERT INTO timelines (title, user_id, created_at, updated_at) VALUES($1, $2, $3, $4) RETURNING id;
@@ -1,13 +1,32 @@
 package timelines
 
-import "time"
+import (
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
+	"time"
+)
 
 type Timeline struct {
-	ID        string
-	UserID    string
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        string    `db:"id,pk,generated"`
+	UserID    string    `db:"user_id"`
+	Name      string    `db:"title"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at,version"`
+}
+
+// ListSpec is the allowlist of the orders timelines can be listed in, newest first by default.
+var ListSpec = pagination.Spec[Timeline]{
+	Columns: []pagination.Column[Timeline]{
+		{Name: "created_at", SQL: "created_at", Value: func(t Timeline) any { return t.CreatedAt }},
+		{Name: "updated_at", SQL: "updated_at", Value: func(t Timeline) any { return t.UpdatedAt }},
+		{Name: "name", SQL: "title", Value: func(t Timeline) any { return t.Name }},
+	},
+	TieBreaker:     pagination.Column[Timeline]{Name: "id", SQL: "id", Value: func(t Timeline) any { return t.ID }},
+	DefaultOrderBy: "-created_at",
+}
+
+// A TimelineFilter narrows down the timelines listed. Blank fields do not filter.
+type TimelineFilter struct {
+	NamePrefix string
 }
 
 type TimelineCreateRequest struct {
@@ -5,6 +5,8 @@ type ListMeta struct {
 	Count int32 `json:"count,omitempty"`
 	Page  int32 `json:"page,omitempty"`
 	More  bool  `json:"more,omitempty"`
+	// NextCursor is the cursor of the next page, when there are more records.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type IdentifiableEntities any
@@ -19,3 +21,17 @@ func NewListResponse[T IdentifiableEntities](records []T) ListResponse[T] {
 		Records: records,
 	}
 }
+
+// NewPagedListResponse creates a [ListResponse] of a page of records, described by meta.
+func NewPagedListResponse[T IdentifiableEntities](records []T, meta ListMeta) ListResponse[T] {
+	return ListResponse[T]{
+		Meta:    meta,
+		Records: records,
+	}
+}
+
+// ListRecords returns the records of the list. It allows encoders that render lists as tables,
+// e.g. CSV, to access the records without knowing the type parameter of the [ListResponse].
+func (l ListResponse[T]) ListRecords() any {
+	return l.Records
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"fmt"
 	achttp "github.com/zhughes3/go-accelerate/internal/pkg/http"
+	"github.com/zhughes3/go-accelerate/internal/pkg/openapi"
+	"net/http"
 	"time"
 )
 
@@ -20,6 +22,11 @@ type options struct {
 
 	prometheusEnabled bool
 
+	openAPIEnabled bool
+	openAPIInfo    openapi.Info
+
+	operationalHandlers []operationalHandler
+
 	customURL    string
 	contextRoot  string
 	timeout      time.Duration
@@ -29,6 +36,9 @@ type options struct {
 
 	requestHandlerSpecs []achttp.RequestHandlerSpec
 
+	// codecs are served by the request handlers and advertised by the OpenAPI document
+	codecs *achttp.CodecRegistry
+
 	version *Version
 
 	beforeHooks []ShutdownErrorHook
@@ -61,6 +71,34 @@ func WithPrometheusEnabled() Option {
 	}
 }
 
+// WithOpenAPIEnabled serves an OpenAPI document, generated from the request handler specs, along with a UI to browse it.
+func WithOpenAPIEnabled(title, description string) Option {
+	return func(o *options) {
+		o.openAPIEnabled = true
+		o.openAPIInfo = openapi.Info{Title: title, Description: description}
+	}
+}
+
+// WithCodecs sets the codecs available to the request handlers and advertised by the OpenAPI document.
+// Defaults to [achttp.DefaultCodecs].
+func WithCodecs(codecs *achttp.CodecRegistry) Option {
+	return func(o *options) {
+		o.codecs = codecs
+	}
+}
+
+// WithOperationalHandler serves a GET handler at the given path under the operational context root, e.g. "/app".
+func WithOperationalHandler(path string, h http.Handler) Option {
+	return func(o *options) {
+		o.operationalHandlers = append(o.operationalHandlers, operationalHandler{path: path, handler: h})
+	}
+}
+
+type operationalHandler struct {
+	path    string
+	handler http.Handler
+}
+
 func WithInsecure() Option {
 	return func(o *options) {
 		o.insecure = true
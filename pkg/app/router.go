@@ -18,6 +18,8 @@ type RouterBuilder struct {
 	authMiddleware     *achttp.HandlerMiddleware
 
 	requestHandlerSpecs []achttp.RequestHandlerSpec
+
+	codecs *achttp.CodecRegistry
 }
 
 func NewRouterBuilder(logger slog.Logger) *RouterBuilder {
@@ -57,11 +59,21 @@ func (b *RouterBuilder) WithRequestHandlerSpecs(specs []achttp.RequestHandlerSpe
 	return b
 }
 
+// WithCodecs sets the codecs available to the request handlers. Defaults to [achttp.DefaultCodecs].
+func (b *RouterBuilder) WithCodecs(codecs *achttp.CodecRegistry) *RouterBuilder {
+	b.codecs = codecs
+	return b
+}
+
 func (b *RouterBuilder) Build() *chi.Mux {
 	if b.router == nil {
 		b.router = chi.NewRouter()
 	}
 
+	if b.codecs == nil {
+		b.codecs = achttp.DefaultCodecs
+	}
+
 	if b.authMiddleware != nil {
 		b.router.Use(*b.authMiddleware)
 	}
@@ -76,7 +88,8 @@ func (b *RouterBuilder) Build() *chi.Mux {
 	for _, spec := range b.requestHandlerSpecs {
 		ep := spec.Endpoint()
 		enc := spec.Encoder()
-		handler := achttp.NewHandler(ep, spec.Decoder(), enc)
+		codecs := b.codecs.Restrict(spec.MediaTypes()...)
+		handler := achttp.NewHandler(ep, spec.Decoder(), enc, achttp.WithCodecs(codecs))
 
 		// TODO prometheus and trace instrumentation
 
@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	achttp "github.com/zhughes3/go-accelerate/internal/pkg/http"
+	"github.com/zhughes3/go-accelerate/internal/pkg/openapi"
 	"github.com/zhughes3/go-accelerate/pkg/app/state"
 	acerrors "github.com/zhughes3/go-accelerate/pkg/errors"
 	"github.com/zhughes3/go-accelerate/pkg/slices"
@@ -26,7 +29,10 @@ import (
 const defaultOpContextRoot = "/app"
 
 const (
+	pathDocs         = "/docs"
+	pathDocsBundle   = "/docs/redoc.standalone.js"
 	pathMetrics      = "/metrics"
+	pathOpenAPI      = "/openapi.json"
 	pathPprof        = "/pprof"
 	pathPprofCmdLine = "/cmdline"
 	pathPprofProfile = "/profile"
@@ -72,13 +78,17 @@ func NewServer(logger slog.Logger, opts ...Option) (*Server, error) {
 	}
 	logger.WithFields(cfg.version.Map()).InfoContext(context.Background(), "Version")
 
+	if cfg.codecs == nil {
+		cfg.codecs = achttp.DefaultCodecs
+	}
+
 	router := chi.NewRouter()
 
 	contextRoot := acurl.CreateFullPath("/", cfg.contextRoot)
 
 	if len(cfg.requestHandlerSpecs) > 0 {
 		appRouter := NewRouterBuilder(logger).WithAuthMiddleware(&cfg.authMiddleware).
-			WithRequestHandlerSpecs(cfg.requestHandlerSpecs).Build()
+			WithRequestHandlerSpecs(cfg.requestHandlerSpecs).WithCodecs(cfg.codecs).Build()
 
 		logger.InfoContextf(context.Background(), "Adding app-specific HTTP handlers to a chi router mounted at %s", contextRoot)
 		router.Mount(contextRoot, appRouter)
@@ -106,7 +116,9 @@ func NewServer(logger slog.Logger, opts ...Option) (*Server, error) {
 		prometheusEnabled:  cfg.prometheusEnabled,
 	}
 
-	registerOperationalHandlers(logger, cfg, router, server)
+	if err := registerOperationalHandlers(logger, cfg, router, server); err != nil {
+		return nil, err
+	}
 
 	logRoutes(logger, router)
 
@@ -245,7 +257,7 @@ func (s *Server) GetState(ctx context.Context) state.State {
 	return s.state
 }
 
-func registerOperationalHandlers(logger slog.Logger, cfg options, router *chi.Mux, s *Server) {
+func registerOperationalHandlers(logger slog.Logger, cfg options, router *chi.Mux, s *Server) error {
 	ctx := context.Background()
 	opContextRoot := defaultOpContextRoot
 
@@ -275,7 +287,61 @@ func registerOperationalHandlers(logger slog.Logger, cfg options, router *chi.Mu
 		router.Get(pprofContextRoot+pathPprofTrace, pprof.Trace)
 	}
 
-	// TODO set up prometheus, tracing
+	if cfg.openAPIEnabled {
+		if err := registerOpenAPIHandlers(logger, cfg, router, opContextRoot); err != nil {
+			return err
+		}
+	}
+
+	if cfg.prometheusEnabled {
+		logger.InfoContextf(ctx, "Using prometheus endpoint at '%s'", opContextRoot+pathMetrics)
+		router.Method(http.MethodGet, opContextRoot+pathMetrics, promhttp.Handler())
+	}
+
+	for _, h := range cfg.operationalHandlers {
+		path := acurl.CreateFullPath(opContextRoot, h.path)
+		logger.InfoContextf(ctx, "Using operational endpoint at '%s'", path)
+		router.Method(http.MethodGet, path, h.handler)
+	}
+
+	// TODO set up tracing
+
+	return nil
+}
+
+func registerOpenAPIHandlers(logger slog.Logger, cfg options, router *chi.Mux, opContextRoot string) error {
+	info := cfg.openAPIInfo
+	info.Version = cfg.version.Version
+
+	doc := openapi.NewDocument(info, acurl.CreateFullPath("/", cfg.contextRoot), cfg.codecs, cfg.requestHandlerSpecs)
+
+	docHandler, err := openapi.NewDocumentHandler(doc)
+	if err != nil {
+		return acerrors.Wrap(err, "problem generating OpenAPI document")
+	}
+
+	logger.InfoContextf(context.Background(), "Using OpenAPI endpoint at '%s'", opContextRoot+pathOpenAPI)
+	router.Method(http.MethodGet, opContextRoot+pathOpenAPI, docHandler)
+
+	bundleHandler, err := openapi.NewUIBundleHandler()
+	if errors.Is(err, openapi.ErrNoUIBundle) {
+		logger.WithError(err).WarnContext(context.Background(), "Not serving the OpenAPI UI")
+		return nil
+	}
+	if err != nil {
+		return acerrors.Wrap(err, "problem loading OpenAPI UI bundle")
+	}
+
+	uiHandler, err := openapi.NewUIHandler(info.Title, opContextRoot+pathOpenAPI, opContextRoot+pathDocsBundle)
+	if err != nil {
+		return acerrors.Wrap(err, "problem generating OpenAPI UI")
+	}
+
+	logger.InfoContextf(context.Background(), "Using OpenAPI UI endpoint at '%s'", opContextRoot+pathDocs)
+	router.Method(http.MethodGet, opContextRoot+pathDocs, uiHandler)
+	router.Method(http.MethodGet, opContextRoot+pathDocsBundle, bundleHandler)
+
+	return nil
 }
 
 func pprofIndexOverride(path string) http.HandlerFunc {
@@ -32,3 +32,142 @@ func HasInvalidInputError(err error) bool {
 	iie := InvalidInputError("")
 	return errors.As(err, &iie)
 }
+
+type NotFoundError string
+
+func (e NotFoundError) StatusCode() int {
+	return http.StatusNotFound
+}
+
+func (e NotFoundError) Error() string {
+	return string(e)
+}
+
+func NewNotFoundError(message string) NotFoundError {
+	return NotFoundError(message)
+}
+
+func NewNotFoundErrorf(format string, args ...any) NotFoundError {
+	return NotFoundError(fmt.Sprintf(format, args...))
+}
+
+func HasNotFoundError(err error) bool {
+	nfe := NotFoundError("")
+	return errors.As(err, &nfe)
+}
+
+type ConflictError string
+
+func (e ConflictError) StatusCode() int {
+	return http.StatusConflict
+}
+
+func (e ConflictError) Error() string {
+	return string(e)
+}
+
+func NewConflictError(message string) ConflictError {
+	return ConflictError(message)
+}
+
+func NewConflictErrorf(format string, args ...any) ConflictError {
+	return ConflictError(fmt.Sprintf(format, args...))
+}
+
+func HasConflictError(err error) bool {
+	ce := ConflictError("")
+	return errors.As(err, &ce)
+}
+
+type TimeoutError string
+
+func (e TimeoutError) StatusCode() int {
+	return http.StatusGatewayTimeout
+}
+
+func (e TimeoutError) Error() string {
+	return string(e)
+}
+
+func NewTimeoutError(message string) TimeoutError {
+	return TimeoutError(message)
+}
+
+func NewTimeoutErrorf(format string, args ...any) TimeoutError {
+	return TimeoutError(fmt.Sprintf(format, args...))
+}
+
+func HasTimeoutError(err error) bool {
+	te := TimeoutError("")
+	return errors.As(err, &te)
+}
+
+// A RetryableError indicates a transient failure where repeating the same operation may succeed.
+type RetryableError string
+
+func (e RetryableError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e RetryableError) Error() string {
+	return string(e)
+}
+
+func (e RetryableError) Retryable() bool {
+	return true
+}
+
+func NewRetryableError(message string) RetryableError {
+	return RetryableError(message)
+}
+
+func NewRetryableErrorf(format string, args ...any) RetryableError {
+	return RetryableError(fmt.Sprintf(format, args...))
+}
+
+func HasRetryableError(err error) bool {
+	re := RetryableError("")
+	return errors.As(err, &re)
+}
+
+type UnsupportedMediaTypeError string
+
+func (e UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+func (e UnsupportedMediaTypeError) Error() string {
+	return string(e)
+}
+
+func NewUnsupportedMediaTypeError(message string) UnsupportedMediaTypeError {
+	return UnsupportedMediaTypeError(message)
+}
+
+func NewUnsupportedMediaTypeErrorf(format string, args ...any) UnsupportedMediaTypeError {
+	return UnsupportedMediaTypeError(fmt.Sprintf(format, args...))
+}
+
+type NotAcceptableError string
+
+func (e NotAcceptableError) StatusCode() int {
+	return http.StatusNotAcceptable
+}
+
+func (e NotAcceptableError) Error() string {
+	return string(e)
+}
+
+func NewNotAcceptableError(message string) NotAcceptableError {
+	return NotAcceptableError(message)
+}
+
+func NewNotAcceptableErrorf(format string, args ...any) NotAcceptableError {
+	return NotAcceptableError(fmt.Sprintf(format, args...))
+}
+
+// A Detailer is an error that carries additional, client safe, key/value details about the failure,
+// e.g. the name of the field that caused a conflict.
+type Detailer interface {
+	Details() map[string]string
+}
@@ -0,0 +1,185 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2
+)
+
+// A Policy controls how an operation is repeated by [Do] after failing. The zero value retries every error forever,
+// with an exponential backoff from 100 milliseconds up to 30 seconds and no jitter, until the context is done.
+type Policy struct {
+	// InitialBackoff is the wait before the first retry. Defaults to 100 milliseconds when not set.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries. Defaults to 30 seconds when not set.
+	MaxBackoff time.Duration
+	// Multiplier is how much longer each retry waits than the previous one. Defaults to 2 when not greater than 1.
+	Multiplier float64
+	// Jitter is the fraction of each wait that is randomized, between 0 and 1. With a jitter of 0.5, the wait is
+	// chosen uniformly between half and all of the exponential backoff, so clients failing together do not retry in
+	// lockstep.
+	Jitter float64
+	// MaxAttempts is the maximum number of times the operation is run, including the first attempt. Attempts are
+	// not limited when not set.
+	MaxAttempts int
+	// MaxElapsedTime is how long the operation is retried for, since the start of the first attempt. No retry is
+	// made that would start after it. Time is not limited when not set.
+	MaxElapsedTime time.Duration
+	// Retryable classifies the errors worth retrying. Every error is retried when not set, except the ones marked
+	// with [Permanent].
+	Retryable func(error) bool
+	// OnRetry is called after a failed attempt, before waiting for the next one, e.g. to log the failure.
+	OnRetry func(ctx context.Context, attempt Attempt)
+}
+
+// An Attempt describes a failed attempt that is about to be retried.
+type Attempt struct {
+	// Number is the number of the failed attempt, starting at 1.
+	Number int
+	// Err is the error of the failed attempt.
+	Err error
+	// Backoff is the wait before the next attempt.
+	Backoff time.Duration
+	// Elapsed is the time since the start of the first attempt.
+	Elapsed time.Duration
+}
+
+// Backoff returns the wait before the given retry, where retry 1 follows the first failed attempt.
+func (p Policy) Backoff(retry int) time.Duration {
+	p = p.withDefaults()
+
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= p.Multiplier
+	}
+
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	jitter := time.Duration(backoff * min(max(p.Jitter, 0), 1))
+	if jitter <= 0 {
+		return time.Duration(backoff)
+	}
+
+	return time.Duration(backoff) - jitter + rand.N(jitter+1)
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = max(defaultMaxBackoff, p.InitialBackoff)
+	}
+	if p.Multiplier <= 1 {
+		p.Multiplier = defaultMultiplier
+	}
+
+	return p
+}
+
+func (p Policy) isRetryable(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// Do runs the func until it succeeds, fails with an error that is not retryable, runs out of attempts or time, or
+// the context is done. The error of the last attempt is returned, wrapped when retrying stopped early, so it can
+// still be inspected with errors.Is and errors.As.
+func Do(ctx context.Context, policy Policy, f func(context.Context) error) error {
+	_, err := DoValue(ctx, policy, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	})
+
+	return err
+}
+
+// DoValue is like [Do], for funcs returning a value.
+func DoValue[T any](ctx context.Context, policy Policy, f func(context.Context) (T, error)) (T, error) {
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		v, err := f(ctx)
+		if err == nil {
+			return v, nil
+		}
+
+		if !policy.isRetryable(err) {
+			return v, unwrapPermanent(err)
+		}
+
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return v, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		backoff := policy.Backoff(attempt)
+		elapsed := time.Since(start)
+		if policy.MaxElapsedTime > 0 && elapsed+backoff > policy.MaxElapsedTime {
+			return v, fmt.Errorf("giving up after %d attempts in %s: %w", attempt, elapsed.Round(time.Millisecond), err)
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(ctx, Attempt{Number: attempt, Err: err, Backoff: backoff, Elapsed: elapsed})
+		}
+
+		if sleepErr := sleepContext(ctx, backoff); sleepErr != nil {
+			return v, fmt.Errorf("retry canceled: %w; original cause: %w", sleepErr, err)
+		}
+	}
+}
+
+// Permanent marks the error as not worth retrying, whatever the [Policy.Retryable] classifier says. [Do] returns the
+// error itself, without the mark.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func unwrapPermanent(err error) error {
+	if permanent, ok := err.(*permanentError); ok {
+		return permanent.err
+	}
+
+	return err
+}
+
+// sleepContext waits for the given duration, returning early with the context's error if it is canceled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -156,8 +156,7 @@ func (l logger) withContext(ctx context.Context, entry *logrus.Entry) *logrus.En
 func (l logger) extractFields(ctx context.Context) map[string]any {
 	m := map[string]any{}
 	for _, extractor := range l.extractors {
-		m := extractor(ctx)
-		for k, v := range m {
+		for k, v := range extractor(ctx) {
 			m[k] = v
 		}
 	}
@@ -3,28 +3,35 @@ package timelines
 import (
 	"context"
 	"github.com/zhughes3/go-accelerate/internal/pkg/postgres"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/outbox"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
 	"github.com/zhughes3/go-accelerate/internal/pkg/v1/timelines"
 	"github.com/zhughes3/go-accelerate/pkg/api"
 	"github.com/zhughes3/go-accelerate/pkg/slog"
 	"time"
 )
 
+// TopicTimelineCreated is the outbox topic of the [IdentifiableTimeline] of every created timeline.
+const TopicTimelineCreated = "timeline.created"
+
 type Service interface { // CreateTimeline validates and stores the given timeline.
 	CreateTimeline(ctx context.Context, userID string, in TimelineCreateReq) (IdentifiableTimeline, error)
 
-	// ListTimelines grabs all timelines associated with the user.
-	ListTimelines(ctx context.Context, userID string) (api.ListResponse[IdentifiableTimeline], error)
+	// ListTimelines grabs a page of the timelines associated with the user.
+	ListTimelines(ctx context.Context, userID string, req TimelineListReq) (api.ListResponse[IdentifiableTimeline], error)
 }
 
 type service struct {
-	logger slog.Logger
-	dao    timelines.DAO
+	logger    slog.Logger
+	dao       timelines.DAO
+	paginator *pagination.Paginator[timelines.Timeline]
 }
 
-func NewService(logger slog.Logger, db postgres.DB) Service {
+func NewService(logger slog.Logger, db postgres.DB, paginationConfig pagination.Config) Service {
 	return withTxService(db, service{
-		logger: logger,
-		dao:    timelines.NewDAO(logger),
+		logger:    logger,
+		dao:       timelines.NewDAO(logger),
+		paginator: pagination.NewPaginator(logger, paginationConfig, timelines.ListSpec),
 	})
 }
 
@@ -38,23 +45,40 @@ func (s service) CreateTimeline(ctx context.Context, userID string, in TimelineC
 		return IdentifiableTimeline{}, err
 	}
 
-	return IdentifiableTimeline{
-		ID: timelineID,
+	timeline := IdentifiableTimeline{
+		ID:     timelineID,
+		UserID: userID,
 		Timeline: Timeline{
 			Name:      in.Name,
 			CreatedAt: now,
 			UpdatedAt: now,
 		},
-	}, nil
+	}
+
+	if err := outbox.Enqueue(ctx, TopicTimelineCreated, timeline); err != nil {
+		return IdentifiableTimeline{}, err
+	}
+
+	return timeline, nil
 }
 
-func (s service) ListTimelines(ctx context.Context, userID string) (api.ListResponse[IdentifiableTimeline], error) {
-	resp, err := s.dao.ListTimelines(ctx, userID)
+func (s service) ListTimelines(ctx context.Context, userID string, req TimelineListReq) (api.ListResponse[IdentifiableTimeline], error) {
+	page, err := s.paginator.Page(req.Page)
+	if err != nil {
+		return api.NewListResponse([]IdentifiableTimeline{}), err
+	}
+
+	resp, err := s.dao.ListTimelines(ctx, userID, timelines.TimelineFilter{NamePrefix: req.NamePrefix}, page)
+	if err != nil {
+		return api.NewListResponse([]IdentifiableTimeline{}), err
+	}
+
+	resp, meta, err := s.paginator.Result(page, resp)
 	if err != nil {
 		return api.NewListResponse([]IdentifiableTimeline{}), err
 	}
 
-	return api.NewListResponse(toIdentifiableTimelines(resp)), nil
+	return api.NewPagedListResponse(toIdentifiableTimelines(resp), meta), nil
 }
 
 func toIdentifiableTimelines(ts []timelines.Timeline) []IdentifiableTimeline {
@@ -89,3 +113,9 @@ type Timeline struct {
 type TimelineCreateReq struct {
 	Name string
 }
+
+type TimelineListReq struct {
+	// NamePrefix lists only the timelines whose name starts with it, when not blank.
+	NamePrefix string
+	Page       pagination.Request
+}
@@ -0,0 +1,65 @@
+package timelines_test
+
+import (
+	"context"
+	"errors"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/pagination"
+	"github.com/zhughes3/go-accelerate/internal/pkg/postgres/postgrestest"
+	"github.com/zhughes3/go-accelerate/pkg/slog"
+	"github.com/zhughes3/go-accelerate/pkg/timelines"
+	"testing"
+)
+
+const queryInsertTimeline = "InsertTimeline"
+
+func newService(db *postgrestest.DB) timelines.Service {
+	return timelines.NewService(slog.Base(), db, pagination.Config{})
+}
+
+func TestCreateTimelineCommits(t *testing.T) {
+	db := postgrestest.NewDB()
+	db.On(queryInsertTimeline, postgrestest.Rows([]string{"id"}, []any{"tl_1"}))
+	db.OnAny(postgrestest.Affected(1))
+
+	timeline, err := newService(db).CreateTimeline(context.Background(), "u_1", timelines.TimelineCreateReq{Name: "Trip"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if timeline.ID != "tl_1" || timeline.UserID != "u_1" || timeline.Name != "Trip" {
+		t.Errorf("unexpected timeline: %+v", timeline)
+	}
+
+	db.AssertRan(t, queryInsertTimeline, 1)
+	db.AssertCommitted(t, 1)
+	db.AssertRolledBack(t, 0)
+	db.AssertNoOpenTx(t)
+}
+
+func TestCreateTimelineRollsBackWhenInsertFails(t *testing.T) {
+	db := postgrestest.NewDB()
+	db.On(queryInsertTimeline, postgrestest.Error(errors.New("boom")))
+
+	if _, err := newService(db).CreateTimeline(context.Background(), "u_1", timelines.TimelineCreateReq{Name: "Trip"}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	db.AssertCommitted(t, 0)
+	db.AssertRolledBack(t, 1)
+	db.AssertNoOpenTx(t)
+}
+
+func TestCreateTimelineRollsBackWhenEnqueueFails(t *testing.T) {
+	db := postgrestest.NewDB()
+	db.On(queryInsertTimeline, postgrestest.Rows([]string{"id"}, []any{"tl_1"}))
+	db.OnAny(postgrestest.Error(errors.New("boom")))
+
+	if _, err := newService(db).CreateTimeline(context.Background(), "u_1", timelines.TimelineCreateReq{Name: "Trip"}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	db.AssertRan(t, queryInsertTimeline, 1)
+	db.AssertCommitted(t, 0)
+	db.AssertRolledBack(t, 1)
+	db.AssertNoOpenTx(t)
+}
@@ -25,8 +25,8 @@ func (t txService) CreateTimeline(ctx context.Context, userID string, in Timelin
 	})
 }
 
-func (t txService) ListTimelines(ctx context.Context, userID string) (api.ListResponse[IdentifiableTimeline], error) {
+func (t txService) ListTimelines(ctx context.Context, userID string, req TimelineListReq) (api.ListResponse[IdentifiableTimeline], error) {
 	return postgres.ExecuteResultFuncInTx(ctx, t.db, func(ctx context.Context) (api.ListResponse[IdentifiableTimeline], error) {
-		return t.delegate.ListTimelines(ctx, userID)
+		return t.delegate.ListTimelines(ctx, userID, req)
 	})
 }